@@ -0,0 +1,276 @@
+// ACME DNS-01 challenge solver
+package ionos
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultPropagationTimeout = 2 * time.Minute
+	defaultPollInterval       = 2 * time.Second
+	defaultChallengeTTL       = 60 * time.Second
+	defaultResolver           = "8.8.8.8:53"
+)
+
+// ChallengeSolver solves ACME DNS-01 challenges using a Provider. Present
+// creates the "_acme-challenge" TXT record and waits until it is served by
+// all authoritative nameservers of the zone. CleanUp removes exactly the
+// record created by Present, so that concurrent challenges for the same
+// name (e.g. for "example.com" and "*.example.com") do not interfere.
+type ChallengeSolver struct {
+	Provider *Provider
+
+	// Nameservers are the addresses (host:port) of the nameservers which are
+	// queried to check propagation. If empty, the authoritative nameservers
+	// of the zone are looked up using Resolver.
+	Nameservers []string
+
+	// Resolver is the address (host:port) of the recursive resolver used to
+	// look up the nameservers of the zone. Defaults to the first nameserver
+	// in /etc/resolv.conf, or 8.8.8.8:53 if that cannot be read.
+	Resolver string
+
+	// PropagationTimeout is the maximum time Present waits for the record
+	// to become visible. Defaults to 2 minutes.
+	PropagationTimeout time.Duration
+
+	// PollInterval is the time between two propagation checks. Defaults to
+	// 2 seconds.
+	PollInterval time.Duration
+
+	// TTL of the challenge record. Defaults to 60 seconds, the minimum
+	// accepted by IONOS.
+	TTL time.Duration
+}
+
+// ChallengeValue returns the value of the TXT record for the given ACME key
+// authorization, as defined in RFC 8555, section 8.4.
+func ChallengeValue(keyAuth string) string {
+	sum := sha256.Sum256([]byte(keyAuth))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// challengeName returns the fully-qualified name of the challenge record
+// for the given domain. A wildcard prefix is removed.
+func challengeName(domain string) string {
+	return "_acme-challenge." + canonicalName(strings.TrimPrefix(domain, "*."))
+}
+
+// Present creates the TXT record with the given value for the DNS-01
+// challenge of domain, and waits until the value is visible on the
+// nameservers. If a record with that value already exists, no new record
+// is created.
+func (s *ChallengeSolver) Present(ctx context.Context, domain, value string) error {
+	fqdn := challengeName(domain)
+	zoneDes, err := s.Provider.findZoneForName(ctx, fqdn)
+	if err != nil {
+		return fmt.Errorf("find zone: %w", err)
+	}
+	if err := s.createChallengeRecord(ctx, zoneDes, fqdn, value); err != nil {
+		return err
+	}
+	return s.Wait(ctx, zoneDes.Name, fqdn, value)
+}
+
+// createChallengeRecord creates the challenge record, unless it exists
+// already. The zone is locked between the check and the creation, so that
+// concurrent calls for the same value create only one record.
+func (s *ChallengeSolver) createChallengeRecord(ctx context.Context, zoneDes zoneDescriptor, fqdn, value string) error {
+	ctx, unlock, err := s.Provider.lockZone(ctx, zoneDes)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	existing, err := s.findChallengeRecords(ctx, zoneDes, fqdn, value)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = defaultChallengeTTL
+	}
+	records, err := s.Provider.applyTTLPolicy([]libdns.Record{
+		libdns.TXT{Name: relativeName(fqdn, zoneDes.Name), Text: value, TTL: ttl},
+	})
+	if err != nil {
+		return err
+	}
+	if err := ValidateRecords(zoneDes.Name, records); err != nil {
+		return err
+	}
+	if _, err := s.Provider.createRecords(ctx, zoneDes, records); err != nil {
+		return fmt.Errorf("create challenge record: %w", err)
+	}
+	return nil
+}
+
+// CleanUp deletes the TXT record with the given value for the DNS-01
+// challenge of domain. Other TXT records with the same name are kept.
+func (s *ChallengeSolver) CleanUp(ctx context.Context, domain, value string) error {
+	fqdn := challengeName(domain)
+	zoneDes, err := s.Provider.findZoneForName(ctx, fqdn)
+	if err != nil {
+		return fmt.Errorf("find zone: %w", err)
+	}
+	ctx, unlock, err := s.Provider.lockZone(ctx, zoneDes)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	existing, err := s.findChallengeRecords(ctx, zoneDes, fqdn, value)
+	if err != nil {
+		return err
+	}
+	for _, r := range existing {
+		if err := s.Provider.Guard.checkChange(zoneDes.Name, r.Name, r.Type); err != nil {
+			return err
+		}
+	}
+	if err := s.Provider.Guard.checkDeletions(zoneDes.Name, len(existing)); err != nil {
+		return err
+	}
+	for _, r := range existing {
+		if err := ionosDeleteRecord(ctx, s.Provider.client(), zoneDes.ID, r.ID); err != nil {
+			return fmt.Errorf("delete challenge record: %w", err)
+		}
+	}
+	return nil
+}
+
+// findChallengeRecords returns the TXT records named fqdn with exactly the
+// given value.
+func (s *ChallengeSolver) findChallengeRecords(ctx context.Context, zoneDes zoneDescriptor, fqdn, value string) ([]zoneRecord, error) {
+	resp, err := ionosGetZone(ctx, s.Provider.client(), zoneDes.ID, "TXT", canonicalName(fqdn), "")
+	if err != nil {
+		return nil, fmt.Errorf("get challenge records: %w", err)
+	}
+	var result []zoneRecord
+	for _, r := range resp.Records {
+		// IONOS returns TXT records quoted
+		if text, err := strconv.Unquote(r.Content); err == nil && text == value {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+// Wait polls the nameservers of zone until all of them serve a TXT record
+// named fqdn with the given value, or the propagation timeout passes.
+func (s *ChallengeSolver) Wait(ctx context.Context, zone, fqdn, value string) error {
+	timeout := s.PropagationTimeout
+	if timeout == 0 {
+		timeout = defaultPropagationTimeout
+	}
+	interval := s.PollInterval
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	nameservers := s.Nameservers
+	if len(nameservers) == 0 {
+		var err error
+		nameservers, err = s.lookupNameservers(ctx, zone)
+		if err != nil {
+			return fmt.Errorf("look up nameservers: %w", err)
+		}
+	}
+
+	var lastErr error
+	for {
+		lastErr = checkTXTPropagation(ctx, nameservers, fqdn, value)
+		if lastErr == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("challenge record not propagated: %w (%v)", ctx.Err(), lastErr)
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (s *ChallengeSolver) resolver() string {
+	if s.Resolver != "" {
+		return s.Resolver
+	}
+	conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil || len(conf.Servers) == 0 {
+		return defaultResolver
+	}
+	return net.JoinHostPort(conf.Servers[0], conf.Port)
+}
+
+// lookupNameservers returns the addresses of the authoritative nameservers
+// of zone, as host:53.
+func (s *ChallengeSolver) lookupNameservers(ctx context.Context, zone string) ([]string, error) {
+	resolver := s.resolver()
+	in, err := exchange(ctx, resolver, dns.Fqdn(zone), dns.TypeNS, true)
+	if err != nil {
+		return nil, err
+	}
+	var nameservers []string
+	for _, rr := range in.Answer {
+		if ns, ok := rr.(*dns.NS); ok {
+			nameservers = append(nameservers, net.JoinHostPort(unFQDN(ns.Ns), "53"))
+		}
+	}
+	if len(nameservers) == 0 {
+		return nil, fmt.Errorf("no NS records found for zone %s", zone)
+	}
+	return nameservers, nil
+}
+
+// checkTXTPropagation returns nil if all nameservers serve a TXT record
+// named fqdn with the given value.
+func checkTXTPropagation(ctx context.Context, nameservers []string, fqdn, value string) error {
+	for _, ns := range nameservers {
+		in, err := exchange(ctx, ns, dns.Fqdn(fqdn), dns.TypeTXT, false)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, rr := range in.Answer {
+			if txt, ok := rr.(*dns.TXT); ok && strings.Join(txt.Txt, "") == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value not yet visible on nameserver %s", ns)
+		}
+	}
+	return nil
+}
+
+func exchange(ctx context.Context, server, name string, qtype uint16, recursive bool) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.RecursionDesired = recursive
+
+	c := &dns.Client{Timeout: 5 * time.Second}
+	in, _, err := c.ExchangeContext(ctx, m, server)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", server, err)
+	}
+	if in.Rcode != dns.RcodeSuccess && in.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("query %s: %s", server, dns.RcodeToString[in.Rcode])
+	}
+	return in, nil
+}
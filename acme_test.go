@@ -0,0 +1,116 @@
+package ionos
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startDNSServer starts a local nameserver answering TXT queries from the
+// records of the given fake zone. It returns the address of the server.
+func startDNSServer(t *testing.T, fs *fakeServer, z *fakeZone) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Authoritative = true
+		q := req.Question[0]
+		for _, r := range fs.records(z) {
+			if r.Type != "TXT" || dns.Fqdn(r.Name) != strings.ToLower(q.Name) {
+				continue
+			}
+			text, _ := strconv.Unquote(r.Content)
+			m.Answer = append(m.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: uint32(r.TTL)},
+				Txt: []string{text},
+			})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	srv := &dns.Server{PacketConn: pc, Handler: handler}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func Test_ChallengeSolver(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "_acme-challenge.www", "TXT", "other-value", 60)
+	ns := startDNSServer(t, fs, zone)
+
+	locker := &recordingLocker{}
+	s := &ChallengeSolver{
+		Provider:           &Provider{AuthAPIToken: "public.secret", Locker: locker},
+		Nameservers:        []string{ns},
+		PropagationTimeout: 5 * time.Second,
+		PollInterval:       10 * time.Millisecond,
+	}
+	value := ChallengeValue("token.thumbprint")
+
+	if err := s.Present(context.TODO(), "*.www.example.com", value); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fs.records(zone)); n != 2 {
+		t.Fatalf("expected 2 records after Present, got %d", n)
+	}
+
+	// Present is idempotent
+	if err := s.Present(context.TODO(), "www.example.com", value); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fs.records(zone)); n != 2 {
+		t.Fatalf("expected 2 records after second Present, got %d", n)
+	}
+
+	if err := s.CleanUp(context.TODO(), "www.example.com", value); err != nil {
+		t.Fatal(err)
+	}
+	records := fs.records(zone)
+	if len(records) != 1 || records[0].Content != `"other-value"` {
+		t.Fatalf("expected only the other challenge record to remain, got %+v", records)
+	}
+
+	// Present and CleanUp lock the zone
+	if len(locker.locked) != 3 || locker.held != 0 {
+		t.Fatalf("expected the zone to be locked 3 times, got %v, %d held", locker.locked, locker.held)
+	}
+}
+
+func Test_ChallengeSolverPropagationTimeout(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	// the nameserver serves a different zone, so the record never shows up
+	ns := startDNSServer(t, fs, fs.addZone("public.secret", "example.org"))
+
+	s := &ChallengeSolver{
+		Provider:           &Provider{AuthAPIToken: "public.secret"},
+		Nameservers:        []string{ns},
+		PropagationTimeout: 100 * time.Millisecond,
+		PollInterval:       10 * time.Millisecond,
+	}
+	if err := s.Present(context.TODO(), "example.com", "value"); err == nil {
+		t.Fatal("expected propagation timeout")
+	}
+	if n := len(fs.records(zone)); n != 1 {
+		t.Fatalf("expected challenge record to be created, got %d records", n)
+	}
+}
+
+func Test_ChallengeValue(t *testing.T) {
+	got := ChallengeValue("token.thumbprint")
+	if want := "61rBZ_4knHblO0MNoxFsXZ_eTFUHum0B6IVRbhvUn5I"; got != want {
+		t.Fatalf("expected challenge value %q, got %q", want, got)
+	}
+}
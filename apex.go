@@ -0,0 +1,62 @@
+// apex and wildcard records
+package ionos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// ErrApexRecord is returned for attempts to delete the NS or SOA records at
+// the apex of a zone, or to replace its SOA record. IONOS manages these
+// records, and the zone does not resolve without them.
+var ErrApexRecord = errors.New("apex NS and SOA records are managed by IONOS")
+
+// IsApex reports whether name is the apex of zone. name is relative to zone,
+// with "@" or "" for the apex, or fully-qualified with a trailing dot.
+func IsApex(name, zone string) bool {
+	return relativeName(libdns.AbsoluteName(name, zone), zone) == "@"
+}
+
+// IsWildcard reports whether name is a wildcard name, i.e. its first label
+// is "*".
+func IsWildcard(name string) bool {
+	return name == "*" || strings.HasPrefix(name, "*.")
+}
+
+// WildcardName returns the wildcard name matching the children of name,
+// which is relative to the zone: "*" for the apex, "*.sub" for "sub".
+func WildcardName(name string) string {
+	if name == "" || name == "@" {
+		return "*"
+	}
+	return "*." + name
+}
+
+// isManagedRecord reports whether rr is one of the SOA or apex NS records,
+// which are managed by IONOS. The type is compared case-insensitively.
+func isManagedRecord(rr libdns.RR) bool {
+	typ := strings.ToUpper(rr.Type)
+	return typ == "SOA" || typ == "NS" && (rr.Name == "@" || rr.Name == "")
+}
+
+// checkApexDelete returns an error wrapping ErrApexRecord if records with
+// the given absolute name and type must not be deleted from zone.
+func checkApexDelete(name, typ, zone string) error {
+	rr := libdns.RR{Name: relativeName(name, zone), Type: strings.ToUpper(typ)}
+	if isManagedRecord(rr) {
+		return fmt.Errorf("%w: refusing to delete %s records of %s", ErrApexRecord, rr.Type, canonicalName(name))
+	}
+	return nil
+}
+
+// checkApexSet returns an error wrapping ErrApexRecord if records with the
+// given absolute name and type must not be replaced.
+func checkApexSet(name, typ string) error {
+	if strings.EqualFold(typ, "SOA") {
+		return fmt.Errorf("%w: refusing to set SOA record of %s", ErrApexRecord, canonicalName(name))
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_ApexAndWildcardNames(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		apex     bool
+		wildcard bool
+		covering string
+	}{
+		{"@", true, false, "*"},
+		{"", true, false, "*"},
+		{"example.com.", true, false, "*.example.com."},
+		{"Example.COM.", true, false, "*.Example.COM."},
+		{"www", false, false, "*.www"},
+		{"*", false, true, "*.*"},
+		{"*.sub", false, true, "*.*.sub"},
+		{"sub.*", false, false, "*.sub.*"},
+	} {
+		if got := IsApex(tc.name, "example.com"); got != tc.apex {
+			t.Errorf("IsApex(%q) = %v, expected %v", tc.name, got, tc.apex)
+		}
+		if got := IsWildcard(tc.name); got != tc.wildcard {
+			t.Errorf("IsWildcard(%q) = %v, expected %v", tc.name, got, tc.wildcard)
+		}
+		if got := WildcardName(tc.name); got != tc.covering {
+			t.Errorf("WildcardName(%q) = %q, expected %q", tc.name, got, tc.covering)
+		}
+	}
+}
+
+func Test_isManagedRecord(t *testing.T) {
+	for _, tc := range []struct {
+		rr       libdns.RR
+		expected bool
+	}{
+		{libdns.RR{Name: "@", Type: "SOA"}, true},
+		{libdns.RR{Name: "@", Type: "soa"}, true},
+		{libdns.RR{Name: "@", Type: "NS"}, true},
+		{libdns.RR{Name: "", Type: "ns"}, true},
+		{libdns.RR{Name: "sub", Type: "ns"}, false},
+		{libdns.RR{Name: "@", Type: "mx"}, false},
+	} {
+		if got := isManagedRecord(tc.rr); got != tc.expected {
+			t.Errorf("isManagedRecord(%+v) = %v, expected %v", tc.rr, got, tc.expected)
+		}
+	}
+}
+
+// newApexZone returns a zone with apex, wildcard and delegation records.
+func newApexZone(t *testing.T) (*fakeServer, *fakeZone) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "@", "SOA", "ns1.ui-dns.de. hostmaster.example.com. 2024010101 28800 7200 604800 300", 3600)
+	fs.addRecord(zone, "@", "NS", "ns1.ui-dns.de", 3600)
+	fs.addRecord(zone, "@", "NS", "ns2.ui-dns.org", 3600)
+	fs.addRecord(zone, "@", "A", "1.1.1.1", 3600)
+	fs.addRecord(zone, "@", "TXT", "v=spf1 -all", 3600)
+	fs.addRecord(zone, "*", "A", "2.2.2.2", 3600)
+	fs.addRecord(zone, "*.sub", "A", "3.3.3.3", 3600)
+	fs.addRecord(zone, "sub", "NS", "ns.sub.example.com", 3600)
+	fs.addRecord(zone, "www", "A", "4.4.4.4", 3600)
+	return fs, zone
+}
+
+// zoneContents returns "name type content" of all records in the zone.
+func zoneContents(fs *fakeServer, z *fakeZone) []string {
+	var result []string
+	for _, r := range fs.records(z) {
+		result = append(result, r.Name+" "+r.Type+" "+r.Content)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// without returns contents without the entries with the given prefixes.
+func without(contents []string, prefixes ...string) []string {
+	var result []string
+next:
+	for _, c := range contents {
+		for _, p := range prefixes {
+			if strings.HasPrefix(c, p) {
+				continue next
+			}
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+func Test_DeleteApexAndWildcardRecords(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		records []libdns.Record
+		removed []string // prefixes of the records expected to be deleted
+		err     error
+	}{
+		{
+			desc:    "apex A by @",
+			records: []libdns.Record{libdns.RR{Name: "@", Type: "A"}},
+			removed: []string{"example.com A"},
+		},
+		{
+			desc:    "apex TXT by fully-qualified name",
+			records: []libdns.Record{libdns.RR{Name: "Example.COM.", Type: "TXT"}},
+			removed: []string{"example.com TXT"},
+		},
+		{
+			desc:    "wildcard only deletes the literal wildcard record",
+			records: []libdns.Record{libdns.RR{Name: "*", Type: "A"}},
+			removed: []string{"*.example.com A"},
+		},
+		{
+			desc:    "wildcard below a subdomain",
+			records: []libdns.Record{libdns.RR{Name: "*.sub", Type: "A", Data: "3.3.3.3"}},
+			removed: []string{"*.sub.example.com A"},
+		},
+		{
+			desc:    "delegation NS below the apex",
+			records: []libdns.Record{libdns.RR{Name: "sub", Type: "NS"}},
+			removed: []string{"sub.example.com NS"},
+		},
+		{
+			desc:    "apex NS RRset",
+			records: []libdns.Record{libdns.RR{Name: "@", Type: "NS"}},
+			err:     ErrApexRecord,
+		},
+		{
+			desc:    "single apex NS record",
+			records: []libdns.Record{libdns.RR{Name: "example.com.", Type: "ns", Data: "ns1.ui-dns.de"}},
+			err:     ErrApexRecord,
+		},
+		{
+			desc:    "SOA",
+			records: []libdns.Record{libdns.RR{Name: "@", Type: "SOA"}},
+			err:     ErrApexRecord,
+		},
+		{
+			desc: "a batch with the apex NS deletes nothing",
+			records: []libdns.Record{
+				libdns.RR{Name: "www", Type: "A"},
+				libdns.RR{Name: "@", Type: "NS"},
+			},
+			err: ErrApexRecord,
+		},
+		{
+			desc:    "empty name",
+			records: []libdns.Record{libdns.RR{Type: "A"}},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs, zone := newApexZone(t)
+			before := zoneContents(fs, zone)
+
+			p := &Provider{AuthAPIToken: "public.secret"}
+			_, err := p.DeleteRecords(context.TODO(), "example.com", tc.records)
+			if tc.err == nil && err != nil || tc.err != nil && !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+
+			expected := without(before, tc.removed...)
+			if got := zoneContents(fs, zone); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+				t.Fatalf("expected records\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func Test_DeleteApexNSByID(t *testing.T) {
+	fs, zone := newApexZone(t)
+	p := &Provider{AuthAPIToken: "public.secret"}
+	records, err := p.GetRecordsDetailed(context.TODO(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var www Record
+	for _, r := range records {
+		if r.RR().Name == "www" {
+			www = r
+		}
+	}
+	for _, r := range records {
+		if r.RR().Type != "NS" || r.RR().Name != "@" {
+			continue
+		}
+		if _, err := p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{r}); !errors.Is(err, ErrApexRecord) {
+			t.Fatalf("expected ErrApexRecord, got %v", err)
+		}
+		if _, err := p.DeleteRecordByID(context.TODO(), "example.com", r.ID); !errors.Is(err, ErrApexRecord) {
+			t.Fatalf("expected ErrApexRecord, got %v", err)
+		}
+		// the batch is checked before anything is deleted
+		if _, err := p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{www, r}); !errors.Is(err, ErrApexRecord) {
+			t.Fatalf("expected ErrApexRecord, got %v", err)
+		}
+	}
+	if n := fs.requestCount("DELETE"); n != 0 {
+		t.Fatalf("expected no DELETE requests, got %d", n)
+	}
+	if len(fs.records(zone)) != len(records) {
+		t.Fatal("records deleted")
+	}
+}
+
+func Test_SetApexAndWildcardRecords(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		record  libdns.Record
+		updated string // expected record after the update
+		err     error
+	}{
+		{
+			desc:    "apex A",
+			record:  libdns.RR{Name: "@", Type: "A", Data: "9.9.9.9", TTL: time.Hour},
+			updated: "example.com A 9.9.9.9",
+		},
+		{
+			desc:    "wildcard A",
+			record:  libdns.RR{Name: "*", Type: "A", Data: "9.9.9.9", TTL: time.Hour},
+			updated: "*.example.com A 9.9.9.9",
+		},
+		{
+			desc:    "new wildcard below a subdomain",
+			record:  libdns.RR{Name: "*.www", Type: "A", Data: "9.9.9.9", TTL: time.Hour},
+			updated: "*.www.example.com A 9.9.9.9",
+		},
+		{
+			desc:   "SOA",
+			record: libdns.RR{Name: "@", Type: "SOA", Data: "ns.example.com. admin.example.com. 1 2 3 4 5", TTL: time.Hour},
+			err:    ErrApexRecord,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			fs, zone := newApexZone(t)
+			before := zoneContents(fs, zone)
+
+			p := &Provider{AuthAPIToken: "public.secret"}
+			_, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{tc.record})
+			if tc.err == nil && err != nil || tc.err != nil && !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+
+			expected := before
+			if tc.updated != "" {
+				prefix := tc.updated[:strings.LastIndex(tc.updated, " ")+1]
+				expected = append(without(before, prefix), tc.updated)
+				sort.Strings(expected)
+			}
+			if got := zoneContents(fs, zone); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+				t.Fatalf("expected records\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// API key validation
+package ionos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// KeyInfo describes an API key which was accepted by IONOS.
+type KeyInfo struct {
+	// PublicPrefix is the public part of the key, as shown in the IONOS
+	// developer portal.
+	PublicPrefix string
+	// Zones lists all zones the key has access to.
+	Zones []libdns.Zone
+}
+
+// InvalidKeyError is returned by Validate when the API key is malformed or
+// was rejected by IONOS.
+type InvalidKeyError struct {
+	Reason string
+	// Err is the API error returned by IONOS, or nil if the key was
+	// rejected locally.
+	Err error
+}
+
+func (e *InvalidKeyError) Error() string {
+	return fmt.Sprintf("invalid IONOS API key: %s", e.Reason)
+}
+
+func (e *InvalidKeyError) Unwrap() error {
+	return e.Err
+}
+
+// parseAPIKey checks that key has the format "publicprefix.secret" and
+// returns the public prefix.
+func parseAPIKey(key string) (string, error) {
+	if key == "" {
+		return "", &InvalidKeyError{Reason: "no key configured"}
+	}
+	if strings.TrimSpace(key) != key {
+		return "", &InvalidKeyError{Reason: "key has leading or trailing whitespace"}
+	}
+	prefix, secret, found := strings.Cut(key, ".")
+	if !found || strings.Contains(secret, ".") {
+		return "", &InvalidKeyError{Reason: `key must have the format "publicprefix.secret"`}
+	}
+	if prefix == "" {
+		return "", &InvalidKeyError{Reason: "public prefix is empty"}
+	}
+	if secret == "" {
+		return "", &InvalidKeyError{Reason: "secret is empty"}
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.') {
+			return "", &InvalidKeyError{Reason: fmt.Sprintf("key contains invalid character %q", c)}
+		}
+	}
+	return prefix, nil
+}
+
+// CheckAPIKey checks that key has the format "publicprefix.secret" used by
+// IONOS API keys, without contacting IONOS. A malformed key results in an
+// *InvalidKeyError.
+func CheckAPIKey(key string) error {
+	_, err := parseAPIKey(key)
+	return err
+}
+
+// Validate checks that AuthAPIToken is well-formed and accepted by IONOS.
+// The key format is checked locally first, so that an obviously broken key
+// does not cause an API call. Then the list of zones is requested, which is
+// the cheapest authenticated call the API offers.
+//
+// If the key is malformed or rejected, an *InvalidKeyError is returned. Other
+// errors (e.g. network problems) are returned as-is, since they say nothing
+// about the key.
+func (p *Provider) Validate(ctx context.Context) (KeyInfo, error) {
+	prefix, err := parseAPIKey(p.AuthAPIToken)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+
+	zones, err := ionosGetAllZones(ctx, p.client())
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) &&
+			(apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+			reason := "rejected by IONOS"
+			if apiErr.Message != "" {
+				reason = fmt.Sprintf("%s: %s", reason, apiErr.Message)
+			}
+			return KeyInfo{}, &InvalidKeyError{Reason: reason, Err: err}
+		}
+		return KeyInfo{}, fmt.Errorf("get all zones: %w", err)
+	}
+
+	info := KeyInfo{PublicPrefix: prefix, Zones: make([]libdns.Zone, len(zones.Zones))}
+	for i, zone := range zones.Zones {
+		info.Zones[i].Name = zone.Name
+	}
+	return info, nil
+}
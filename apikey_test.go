@@ -0,0 +1,92 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_parseAPIKey(t *testing.T) {
+	testCases := []struct {
+		key    string
+		prefix string
+		valid  bool
+	}{
+		{key: "0123456789abcdef.s3cr3t-Secret_x", prefix: "0123456789abcdef", valid: true},
+		{key: "", valid: false},
+		{key: "nodot", valid: false},
+		{key: ".secret", valid: false},
+		{key: "prefix.", valid: false},
+		{key: "a.b.c", valid: false},
+		{key: " prefix.secret", valid: false},
+		{key: "prefix.secret\n", valid: false},
+		{key: "pre fix.secret", valid: false},
+		{key: "prefix.sec/ret", valid: false},
+	}
+
+	for _, c := range testCases {
+		prefix, err := parseAPIKey(c.key)
+		if c.valid {
+			if err != nil {
+				t.Errorf("parseAPIKey(%q): unexpected error: %v", c.key, err)
+			}
+			if prefix != c.prefix {
+				t.Errorf("parseAPIKey(%q): expected prefix %q, got %q", c.key, c.prefix, prefix)
+			}
+			continue
+		}
+		var keyErr *InvalidKeyError
+		if !errors.As(err, &keyErr) {
+			t.Errorf("parseAPIKey(%q): expected InvalidKeyError, got %v", c.key, err)
+		}
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("public.secret", "example.com")
+	fs.addZone("public.secret", "example.org")
+	fs.addZone("other.secret", "example.net")
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	info, err := p.Validate(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PublicPrefix != "public" {
+		t.Fatalf("expected public prefix %q, got %q", "public", info.PublicPrefix)
+	}
+	if len(info.Zones) != 2 || info.Zones[0].Name != "example.com" || info.Zones[1].Name != "example.org" {
+		t.Fatalf("unexpected zones %+v", info.Zones)
+	}
+}
+
+func Test_ValidateRejectedKey(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("public.secret", "example.com")
+
+	p := &Provider{AuthAPIToken: "public.wrong"}
+	_, err := p.Validate(context.TODO())
+
+	var keyErr *InvalidKeyError
+	if !errors.As(err, &keyErr) {
+		t.Fatalf("expected InvalidKeyError, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 401 || apiErr.Code != "UNAUTHORIZED" {
+		t.Fatalf("expected wrapped 401 APIError, got %+v", keyErr.Err)
+	}
+}
+
+func Test_ValidateMalformedKeyMakesNoRequest(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("public.secret", "example.com")
+
+	p := &Provider{AuthAPIToken: "malformed"}
+	if _, err := p.Validate(context.TODO()); err == nil {
+		t.Fatal("expected error for malformed key")
+	}
+	if n := fs.requestCount("GET"); n != 0 {
+		t.Fatalf("expected no request, got %d", n)
+	}
+}
@@ -0,0 +1,88 @@
+// Package caddy registers the IONOS DNS provider as the Caddy module
+// dns.providers.ionos.
+package caddy
+
+import (
+	"fmt"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+
+	"github.com/libdns/ionos"
+)
+
+// Provider wraps the IONOS provider as a Caddy module. In JSON config, the
+// API token is set with the key "auth_api_token".
+type Provider struct{ *ionos.Provider }
+
+func init() {
+	caddy.RegisterModule(Provider{})
+}
+
+// CaddyModule returns the Caddy module information.
+func (Provider) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "dns.providers.ionos",
+		New: func() caddy.Module { return &Provider{new(ionos.Provider)} },
+	}
+}
+
+// Provision replaces placeholders like {env.IONOS_TOKEN} in the API token.
+func (p *Provider) Provision(ctx caddy.Context) error {
+	repl := caddy.NewReplacer()
+	p.Provider.AuthAPIToken = repl.ReplaceAll(p.Provider.AuthAPIToken, "")
+	return nil
+}
+
+// Validate checks the format of the API token. The token is not sent to
+// IONOS, so that configurations can be validated offline.
+func (p *Provider) Validate() error {
+	if err := ionos.CheckAPIKey(p.Provider.AuthAPIToken); err != nil {
+		return fmt.Errorf("dns.providers.ionos: %w", err)
+	}
+	return nil
+}
+
+// UnmarshalCaddyfile sets up the DNS provider from Caddyfile tokens. Syntax:
+//
+//	ionos [<api_token>] {
+//	    api_token <api_token>
+//	}
+func (p *Provider) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.Next() {
+		if d.NextArg() {
+			p.Provider.AuthAPIToken = d.Val()
+		}
+		if d.NextArg() {
+			return d.ArgErr()
+		}
+		for nesting := d.Nesting(); d.NextBlock(nesting); {
+			switch d.Val() {
+			case "api_token":
+				if p.Provider.AuthAPIToken != "" {
+					return d.Err("API token already set")
+				}
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				p.Provider.AuthAPIToken = d.Val()
+				if d.NextArg() {
+					return d.ArgErr()
+				}
+			default:
+				return d.Errf("unrecognized subdirective '%s'", d.Val())
+			}
+		}
+	}
+	if p.Provider.AuthAPIToken == "" {
+		return d.Err("missing API token")
+	}
+	return nil
+}
+
+// Interface guards
+var (
+	_ caddyfile.Unmarshaler = (*Provider)(nil)
+	_ caddy.Provisioner     = (*Provider)(nil)
+	_ caddy.Validator       = (*Provider)(nil)
+)
@@ -0,0 +1,70 @@
+package caddy
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+
+	"github.com/libdns/ionos"
+)
+
+func Test_UnmarshalCaddyfile(t *testing.T) {
+	testCases := []struct {
+		input string
+		token string
+		valid bool
+	}{
+		{input: `ionos prefix.secret`, token: "prefix.secret", valid: true},
+		{input: "ionos {\n api_token {env.IONOS_TOKEN}\n}", token: "{env.IONOS_TOKEN}", valid: true},
+		{input: `ionos`, valid: false},
+		{input: `ionos a.b c.d`, valid: false},
+		{input: "ionos a.b {\n api_token c.d\n}", valid: false},
+		{input: "ionos {\n token a.b\n}", valid: false},
+	}
+
+	for _, c := range testCases {
+		p := &Provider{new(ionos.Provider)}
+		err := p.UnmarshalCaddyfile(caddyfile.NewTestDispenser(c.input))
+		if !c.valid {
+			if err == nil {
+				t.Errorf("%q: expected error", c.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.input, err)
+			continue
+		}
+		if p.Provider.AuthAPIToken != c.token {
+			t.Errorf("%q: expected token %q, got %q", c.input, c.token, p.Provider.AuthAPIToken)
+		}
+	}
+}
+
+func Test_ProvisionAndValidate(t *testing.T) {
+	t.Setenv("IONOS_TOKEN", "prefix.secret")
+
+	p := &Provider{new(ionos.Provider)}
+	if err := json.Unmarshal([]byte(`{"auth_api_token": "{env.IONOS_TOKEN}"}`), p); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := caddy.NewContext(caddy.Context{Context: context.Background()})
+	defer cancel()
+	if err := p.Provision(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if p.Provider.AuthAPIToken != "prefix.secret" {
+		t.Fatalf("expected placeholder to be replaced, got %q", p.Provider.AuthAPIToken)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Provider.AuthAPIToken = "malformed"
+	if err := p.Validate(); err == nil {
+		t.Fatal("expected error for malformed token")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,6 +17,40 @@ const (
 	APIEndpoint = "https://api.hosting.ionos.com/dns/v1"
 )
 
+// apiEndpoint is the base URL used for all API requests. It is only changed
+// by tests, which point the client to a local fake server.
+var apiEndpoint = APIEndpoint
+
+// APIError is returned when the IONOS API answers with a non-2xx status code.
+// Code and Message are taken from the error document returned by IONOS, if
+// present.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (%d)", http.StatusText(e.StatusCode), e.StatusCode)
+}
+
+// IONOS returns errors as a JSON array, e.g.
+// [{"code":"UNAUTHORIZED","message":"Missing or invalid API key."}]
+type errorResponse []struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+	var errs errorResponse
+	if err := json.Unmarshal(body, &errs); err == nil && len(errs) > 0 {
+		apiErr.Code = errs[0].Code
+		apiErr.Message = errs[0].Message
+	}
+	return apiErr
+}
+
 type getAllZonesResponse struct {
 	Zones []zoneDescriptor
 }
@@ -71,40 +106,68 @@ func debug(s string) {
 	}
 }
 
-func doRequest(token string, request *http.Request) ([]byte, error) {
-	request.Header.Add("Content-Type", "application/json")
+// doRequest sends request, waiting for the rate limiter of c first.
+// Requests answered with 429 Too Many Requests are retried up to c.retries
+// times, after the delay requested by IONOS, during which all requests
+// with the same token are held back.
+func doRequest(c apiClient, request *http.Request) ([]byte, error) {
+	request.Header.Set("Content-Type", "application/json")
 	debug(fmt.Sprintf("HTTP req: %+v", request))
-	request.Header.Add("X-API-Key", token)
+	request.Header.Set("X-API-Key", c.token)
+	if c.limiter == nil {
+		c.limiter = &rateLimiter{}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(request.Context()); err != nil {
+			return nil, err
+		}
+		body, header, err := sendRequest(request)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || attempt >= c.retries {
+			return body, err
+		}
+		delay := retryDelay(header, attempt)
+		debug(fmt.Sprintf("throttled, retrying in %v", delay))
+		c.limiter.pause(delay)
+		if request.GetBody != nil {
+			if request.Body, err = request.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
 
+func sendRequest(request *http.Request) ([]byte, http.Header, error) {
 	client := &http.Client{} // no timeout set because request is w/ context
 	response, err := client.Do(request)
 	debug(fmt.Sprintf("HTTP res: %+v, err=%+v", response, err))
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer response.Body.Close()
 
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read http response body: %w", err)
+		return nil, response.Header, fmt.Errorf("read http response body: %w", err)
 	}
 	debug(fmt.Sprintf("<<< HTTP res-body: %s", body))
 
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return nil, fmt.Errorf("%s (%d)", http.StatusText(response.StatusCode), response.StatusCode)
+		return nil, response.Header, newAPIError(response.StatusCode, body)
 	}
-	return body, nil
+	return body, response.Header, nil
 }
 
 // GET /v1/zones
-func ionosGetAllZones(ctx context.Context, token string) (getAllZonesResponse, error) {
-	uri := fmt.Sprintf("%s/zones", APIEndpoint)
+func ionosGetAllZones(ctx context.Context, c apiClient) (getAllZonesResponse, error) {
+	uri := fmt.Sprintf("%s/zones", apiEndpoint)
 	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
 	if err != nil {
 		return getAllZonesResponse{}, err
 	}
-	data, err := doRequest(token, req)
+	data, err := doRequest(c, req)
 	if err != nil {
 		return getAllZonesResponse{}, err
 	}
@@ -116,11 +179,10 @@ func ionosGetAllZones(ctx context.Context, token string) (getAllZonesResponse, e
 }
 
 // ionosGetZone reads the contents of zone by it's IONOS zoneID, optionally filtering for
-// a specific recordType and recordName (IONOS API allows to filter for name,
-// type, suffix).
+// a specific recordType, recordName and name suffix.
 // GET /v1/zones/{zoneId}
-func ionosGetZone(ctx context.Context, token string, zoneID string, recordType, recordName string) (getZoneResponse, error) {
-	u, err := url.Parse(APIEndpoint)
+func ionosGetZone(ctx context.Context, c apiClient, zoneID string, recordType, recordName, suffix string) (getZoneResponse, error) {
+	u, err := url.Parse(apiEndpoint)
 	if err != nil {
 		return getZoneResponse{}, err
 	}
@@ -132,13 +194,16 @@ func ionosGetZone(ctx context.Context, token string, zoneID string, recordType,
 	if recordName != "" {
 		queryString.Set("recordName", recordName)
 	}
+	if suffix != "" {
+		queryString.Set("suffix", suffix)
+	}
 	u.RawQuery = queryString.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return getZoneResponse{}, err
 	}
-	data, err := doRequest(token, req)
+	data, err := doRequest(c, req)
 	var result getZoneResponse
 	if err != nil {
 		return result, err
@@ -148,33 +213,61 @@ func ionosGetZone(ctx context.Context, token string, zoneID string, recordType,
 	return result, err
 }
 
-// ionosFindRecordsInZone is a convenience function to search all records in the
-// given zone for a record with the given name and type and returns this record
-// on success
-func ionosFindRecordsInZone(ctx context.Context, token string, zoneID, typ, name string) ([]zoneRecord, error) {
-	resp, err := ionosGetZone(ctx, token, zoneID, typ, name)
+// ionosGetRecord reads a single record by it's IONOS record ID
+// GET /v1/zones/{zoneId}/records/{recordId}
+func ionosGetRecord(ctx context.Context, c apiClient, zoneID, id string) (zoneRecord, error) {
+	if id == "" {
+		return zoneRecord{}, fmt.Errorf("no record id provided")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		fmt.Sprintf("%s/zones/%s/records/%s", apiEndpoint, zoneID, id), nil)
 	if err != nil {
-		return nil, err
+		return zoneRecord{}, err
 	}
-	if len(resp.Records) < 1 {
-		return nil, fmt.Errorf("record not found in zone")
+	data, err := doRequest(c, req)
+	var result zoneRecord
+	if err != nil {
+		return result, err
 	}
-	return resp.Records, nil
+
+	err = json.Unmarshal(data, &result)
+	return result, err
 }
 
 // ionosDeleteRecord deletes the given record
 // DELETE /v1/zones/{zoneId}/records/{recordId}
-func ionosDeleteRecord(ctx context.Context, token string, zoneID, id string) error {
+func ionosDeleteRecord(ctx context.Context, c apiClient, zoneID, id string) error {
 	if id == "" {
 		return fmt.Errorf("no record id provided")
 	}
 
+	// in dry-run mode, read the record instead to make sure it exists
+	if log := dryRunLog(ctx); log != nil {
+		if created, ok := log.createdRecord(id); ok {
+			log.add(newDryRunChange(SyncDelete, zoneID, id, created))
+			return nil
+		}
+		existing, err := ionosGetRecord(ctx, c, zoneID, id)
+		if err != nil {
+			return err
+		}
+		log.add(newDryRunChange(SyncDelete, zoneID, id, record{
+			Name:    existing.Name,
+			Type:    existing.Type,
+			Content: existing.Content,
+			TTL:     &existing.TTL,
+			Prio:    existing.Prio,
+		}))
+		return nil
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "DELETE",
-		fmt.Sprintf("%s/zones/%s/records/%s", APIEndpoint, zoneID, id), nil)
+		fmt.Sprintf("%s/zones/%s/records/%s", apiEndpoint, zoneID, id), nil)
 	if err != nil {
 		return err
 	}
-	_, err = doRequest(token, req)
+	_, err = doRequest(c, req)
 	return err
 }
 
@@ -182,23 +275,27 @@ func ionosDeleteRecord(ctx context.Context, token string, zoneID, id string) err
 // POST /v1/zones/{zoneId}/records
 func ionosCreateRecords(
 	ctx context.Context,
-	token string,
+	c apiClient,
 	zoneID string,
 	records []record,
 ) ([]zoneRecord, error) {
+	if log := dryRunLog(ctx); log != nil {
+		return dryRunCreateRecords(log, zoneID, records), nil
+	}
+
 	reqBuffer, err := json.Marshal(records)
 	if err != nil {
 		return nil, err
 	}
 
-	uri := fmt.Sprintf("%s/zones/%s/records", APIEndpoint, zoneID)
+	uri := fmt.Sprintf("%s/zones/%s/records", apiEndpoint, zoneID)
 	req, err := http.NewRequestWithContext(ctx, "POST", uri, bytes.NewBuffer(reqBuffer))
 	if err != nil {
 		return nil, err
 	}
 
 	// as result of the POST, a zoneRecord array is returned
-	res, err := doRequest(token, req)
+	res, err := doRequest(c, req)
 	if err != nil {
 		return nil, err
 	}
@@ -213,24 +310,35 @@ func ionosCreateRecords(
 // ionosUpdateRecord updates the record with id `id` in the given zone
 // TODO check TTL
 // PUT /v1/zones/{zoneId}/records/{recordId}
-func ionosUpdateRecord(ctx context.Context, token string, zoneID, id string, r record) error {
+func ionosUpdateRecord(ctx context.Context, c apiClient, zoneID, id string, r record) error {
 	if id == "" {
 		return fmt.Errorf("no record id provided")
 	}
 
+	// in dry-run mode, read the record instead to make sure it exists
+	if log := dryRunLog(ctx); log != nil {
+		if !log.updateCreated(id, r) {
+			if _, err := ionosGetRecord(ctx, c, zoneID, id); err != nil {
+				return err
+			}
+		}
+		log.add(newDryRunChange(SyncUpdate, zoneID, id, r))
+		return nil
+	}
+
 	reqBuffer, err := json.Marshal(r)
 	if err != nil {
 		return fmt.Errorf("marshal record for update: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "PUT",
-		fmt.Sprintf("%s/zones/%s/records/%s", APIEndpoint, zoneID, id),
+		fmt.Sprintf("%s/zones/%s/records/%s", apiEndpoint, zoneID, id),
 		bytes.NewBuffer(reqBuffer))
 	if err != nil {
 		return err
 	}
 
 	// according to API doc, no response returned here
-	_, err = doRequest(token, req)
+	_, err = doRequest(c, req)
 	return err
 }
\ No newline at end of file
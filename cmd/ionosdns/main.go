@@ -0,0 +1,405 @@
+// ionosdns is a command line tool to manage zones and records using the
+// IONOS DNS API.
+//
+// Usage:
+//
+//	ionosdns [flags] zones list
+//	ionosdns [flags] zones export <zone>
+//	ionosdns [flags] zones import [-replace] [-include] <zone> <file>
+//	ionosdns [flags] zones sync [-yes] [-max-deletions n] [-protect names] [-include] <zone> <file>
+//	ionosdns [flags] records get <zone>
+//	ionosdns [flags] records append <zone> <name> <type> <data>
+//	ionosdns [flags] records set <zone> <name> <type> <data>
+//	ionosdns [flags] records delete <zone> <name> <type> [<data>]
+//
+// The API token is taken from the -token flag, the file given with
+// -token-file, or the environment variable LIBDNS_IONOS_TOKEN, in this
+// order.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	"github.com/libdns/ionos"
+)
+
+// exit codes
+const (
+	exitOK       = 0
+	exitError    = 1 // any error not covered below, e.g. network problems
+	exitUsage    = 2 // invalid command line
+	exitAuth     = 3 // API key malformed or rejected (401, 403)
+	exitNotFound = 4 // zone or record not found (404)
+	exitRejected = 5 // invalid records, or request rejected by IONOS (other 4xx)
+	exitServer   = 6 // IONOS server error (5xx)
+)
+
+const usage = `Usage: ionosdns [flags] <command>
+
+Commands:
+  zones list
+  zones export <zone>
+  zones import [-replace] [-include] <zone> <file>
+  zones sync [-yes] [-max-deletions n] [-protect names] [-include] <zone> <file>
+  records get <zone>
+  records append <zone> <name> <type> <data>
+  records set <zone> <name> <type> <data>
+  records delete <zone> <name> <type> [<data>]
+
+Names are relative to the zone, use @ for the zone apex.
+
+Flags:
+`
+
+// endpointEnv names the environment variable overriding the base URL of the
+// IONOS API, e.g. to run the tool against a test server. It is not part of
+// the documented interface.
+const endpointEnv = "IONOSDNS_ENDPOINT"
+
+// usageError is returned for invalid command lines.
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+type config struct {
+	token     string
+	tokenFile string
+	output    string
+	ttl       time.Duration
+	dryRun    bool
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var cfg config
+	fs := flag.NewFlagSet("ionosdns", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&cfg.token, "token", "", "IONOS API token (publicprefix.secret)")
+	fs.StringVar(&cfg.tokenFile, "token-file", "", "read the IONOS API token from `file`")
+	fs.StringVar(&cfg.output, "output", "table", "output format: table, json or zonefile")
+	fs.DurationVar(&cfg.ttl, "ttl", 0, "TTL of created or updated records (default: IONOS default)")
+	fs.BoolVar(&cfg.dryRun, "dry-run", false, "only show the changes that would be made")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	ctx := context.Background()
+	var dryRunLog *ionos.DryRunLog
+	if cfg.dryRun {
+		ctx, dryRunLog = ionos.WithDryRun(ctx)
+	}
+
+	err := execute(ctx, cfg, fs.Args(), stdin, stdout)
+	if dryRunLog != nil {
+		printDryRunLog(stderr, dryRunLog)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "ionosdns: %v\n", err)
+		var uerr usageError
+		if errors.As(err, &uerr) {
+			fs.Usage()
+		}
+	}
+	return exitCode(err)
+}
+
+// printDryRunLog writes the changes skipped in dry-run mode to w.
+func printDryRunLog(w io.Writer, log *ionos.DryRunLog) {
+	changes := log.Changes()
+	fmt.Fprintf(w, "dry-run: %d changes skipped\n", len(changes))
+	for _, c := range changes {
+		id := c.RecordID
+		if id == "" {
+			id = "-"
+		}
+		fmt.Fprintf(w, "would %s\t%s\t%s\t%d\t%s\t%s\n", c.Action, id, c.Name, c.TTL, c.Type, c.Content)
+	}
+}
+
+// exitCode maps err to the exit code of the program.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var uerr usageError
+	if errors.As(err, &uerr) {
+		return exitUsage
+	}
+	var keyErr *ionos.InvalidKeyError
+	if errors.As(err, &keyErr) {
+		return exitAuth
+	}
+	if errors.Is(err, ionos.ErrZoneNotFound) {
+		return exitNotFound
+	}
+	var validationErr *ionos.ValidationError
+	if errors.As(err, &validationErr) {
+		return exitRejected
+	}
+	var apiErr *ionos.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			return exitAuth
+		case apiErr.StatusCode == http.StatusNotFound:
+			return exitNotFound
+		case apiErr.StatusCode >= 500:
+			return exitServer
+		case apiErr.StatusCode >= 400:
+			return exitRejected
+		}
+	}
+	return exitError
+}
+
+// redirectAPI sends the requests for the IONOS API to endpoint instead, until
+// restore is called. The provider uses the default HTTP transport, which is
+// wrapped for this.
+func redirectAPI(endpoint string) (restore func(), err error) {
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", endpointEnv, err)
+	}
+	next := http.DefaultTransport
+	http.DefaultTransport = &endpointTransport{endpoint: strings.TrimSuffix(endpoint, "/"), next: next}
+	return func() { http.DefaultTransport = next }, nil
+}
+
+// endpointTransport rewrites the URLs of requests for ionos.APIEndpoint to
+// endpoint.
+type endpointTransport struct {
+	endpoint string
+	next     http.RoundTripper
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rest, ok := strings.CutPrefix(req.URL.String(), ionos.APIEndpoint)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	u, err := url.Parse(t.endpoint + rest)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.URL = u
+	req.Host = u.Host
+	return t.next.RoundTrip(req)
+}
+
+// loadToken returns the API token from the command line, the token file or
+// the environment.
+func loadToken(cfg config) (string, error) {
+	if cfg.token != "" {
+		return cfg.token, nil
+	}
+	if cfg.tokenFile != "" {
+		data, err := os.ReadFile(cfg.tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("read token file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if token := os.Getenv("LIBDNS_IONOS_TOKEN"); token != "" {
+		return token, nil
+	}
+	return "", usageError{"no API token given, use -token, -token-file or LIBDNS_IONOS_TOKEN"}
+}
+
+func execute(ctx context.Context, cfg config, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) < 2 {
+		return usageError{"missing command"}
+	}
+	out, err := newOutput(cfg.output, stdout)
+	if err != nil {
+		return err
+	}
+
+	token, err := loadToken(cfg)
+	if err != nil {
+		return err
+	}
+	if err := ionos.CheckAPIKey(token); err != nil {
+		return err
+	}
+	if endpoint := os.Getenv(endpointEnv); endpoint != "" {
+		restore, err := redirectAPI(endpoint)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+	p := &ionos.Provider{AuthAPIToken: token}
+
+	switch args[0] + " " + args[1] {
+	case "zones list":
+		if len(args) != 2 {
+			return usageError{"zones list takes no arguments"}
+		}
+		zones, err := p.ListZones(ctx)
+		if err != nil {
+			return err
+		}
+		return out.zones(zones)
+
+	case "zones export":
+		if len(args) != 3 {
+			return usageError{"usage: zones export <zone>"}
+		}
+		return p.ExportZone(ctx, args[2], stdout)
+
+	case "zones import":
+		return importZone(ctx, p, args[2:], out)
+
+	case "zones sync":
+		return syncZone(ctx, p, args[2:], stdin, stdout)
+
+	case "records get":
+		if len(args) != 3 {
+			return usageError{"usage: records get <zone>"}
+		}
+		records, err := p.GetRecords(ctx, args[2])
+		if err != nil {
+			return err
+		}
+		return out.records(args[2], records)
+
+	case "records append", "records set":
+		if len(args) != 6 {
+			return usageError{fmt.Sprintf("usage: records %s <zone> <name> <type> <data>", args[1])}
+		}
+		record, err := parseRecord(args[3], args[4], args[5], cfg.ttl)
+		if err != nil {
+			return err
+		}
+		op := p.AppendRecords
+		if args[1] == "set" {
+			op = p.SetRecords
+		}
+		records, err := op(ctx, args[2], []libdns.Record{record})
+		if err != nil {
+			return err
+		}
+		return out.records(args[2], records)
+
+	case "records delete":
+		if len(args) != 5 && len(args) != 6 {
+			return usageError{"usage: records delete <zone> <name> <type> [<data>]"}
+		}
+		data := ""
+		if len(args) == 6 {
+			data = args[5]
+		}
+		rr := libdns.RR{Name: args[3], Type: strings.ToUpper(args[4]), Data: data}
+		records, err := p.DeleteRecords(ctx, args[2], []libdns.Record{rr})
+		if err != nil {
+			return err
+		}
+		return out.records(args[2], records)
+	}
+	return usageError{fmt.Sprintf("unknown command %q", args[0]+" "+args[1])}
+}
+
+// importZone implements "zones import". The created and deleted records are
+// written to out.
+func importZone(ctx context.Context, p *ionos.Provider, args []string, out *output) error {
+	fs := flag.NewFlagSet("zones import", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	replace := fs.Bool("replace", false, "delete records which are not in the zone file")
+	include := fs.Bool("include", false, "allow $INCLUDE directives in the zone file")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return usageError{"usage: zones import [-replace] [-include] <zone> <file>"}
+	}
+	zone, filename := fs.Arg(0), fs.Arg(1)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	result, err := p.ImportZone(ctx, zone, f, ionos.ImportOptions{Filename: filename, AllowInclude: *include, Replace: *replace})
+	if err != nil {
+		return err
+	}
+	return out.importResult(zone, result)
+}
+
+// syncZone implements "zones sync". The plan is printed, and only applied
+// after confirmation on stdin, or with -yes.
+func syncZone(ctx context.Context, p *ionos.Provider, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("zones sync", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	yes := fs.Bool("yes", false, "apply the plan without confirmation")
+	maxDeletions := fs.Int("max-deletions", 0, "refuse plans deleting more than n records (0: no limit)")
+	protect := fs.String("protect", "", "comma separated names which are never changed, e.g. @,_dmarc")
+	include := fs.Bool("include", false, "allow $INCLUDE directives in the zone file")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return usageError{"usage: zones sync [-yes] [-max-deletions n] [-protect names] [-include] <zone> <file>"}
+	}
+	zone, filename := fs.Arg(0), fs.Arg(1)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	desired, err := ionos.ReadZoneFile(f, zone, filename, *include)
+	if err != nil {
+		return err
+	}
+
+	opts := ionos.SyncOptions{MaxDeletions: *maxDeletions}
+	if *protect != "" {
+		opts.ProtectedNames = strings.Split(*protect, ",")
+	}
+	plan, err := p.PlanSync(ctx, zone, desired, opts)
+	if plan != nil {
+		fmt.Fprint(stdout, plan)
+	}
+	if err != nil || plan.Empty() {
+		return err
+	}
+
+	if !*yes {
+		fmt.Fprint(stdout, "apply these changes? [y/N] ")
+		answer, _ := bufio.NewReader(stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Fprintln(stdout, "aborted, nothing changed")
+			return nil
+		}
+	}
+	applied, err := p.ApplySync(ctx, plan)
+	fmt.Fprintf(stdout, "applied %d of %d changes\n", len(applied), len(plan.Changes))
+	return err
+}
+
+// parseRecord builds a libdns record from the command line arguments.
+func parseRecord(name, typ, data string, ttl time.Duration) (libdns.Record, error) {
+	record, err := libdns.RR{Name: name, Type: strings.ToUpper(typ), Data: data, TTL: ttl}.Parse()
+	if err != nil {
+		return nil, usageError{fmt.Sprintf("invalid %s record: %v", typ, err)}
+	}
+	return record, nil
+}
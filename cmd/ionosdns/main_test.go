@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	"github.com/libdns/ionos"
+)
+
+func Test_exitCode(t *testing.T) {
+	testCases := []struct {
+		err  error
+		code int
+	}{
+		{err: nil, code: exitOK},
+		{err: fmt.Errorf("boom"), code: exitError},
+		{err: usageError{"bad"}, code: exitUsage},
+		{err: &ionos.InvalidKeyError{Reason: "malformed"}, code: exitAuth},
+		{err: fmt.Errorf("find zone: %w", ionos.ErrZoneNotFound), code: exitNotFound},
+		{err: fmt.Errorf("get: %w", &ionos.APIError{StatusCode: 401}), code: exitAuth},
+		{err: fmt.Errorf("get: %w", &ionos.APIError{StatusCode: 404}), code: exitNotFound},
+		{err: fmt.Errorf("get: %w", &ionos.APIError{StatusCode: 400}), code: exitRejected},
+		{err: &ionos.ValidationError{}, code: exitRejected},
+		{err: fmt.Errorf("get: %w", &ionos.APIError{StatusCode: 503}), code: exitServer},
+	}
+	for _, c := range testCases {
+		if code := exitCode(c.err); code != c.code {
+			t.Errorf("exitCode(%v): expected %d, got %d", c.err, c.code, code)
+		}
+	}
+}
+
+func Test_runUsageErrors(t *testing.T) {
+	t.Setenv("LIBDNS_IONOS_TOKEN", "prefix.secret")
+	testCases := [][]string{
+		{},
+		{"zones"},
+		{"records", "get"},
+		{"records", "frobnicate", "example.com"},
+		{"-output", "yaml", "zones", "list"},
+		{"records", "append", "example.com", "www", "A", "not-an-ip"},
+	}
+	for _, args := range testCases {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, nil, &stdout, &stderr); code != exitUsage {
+			t.Errorf("run(%q): expected exit code %d, got %d (%s)", args, exitUsage, code, stderr.String())
+		}
+	}
+}
+
+func Test_runMalformedToken(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-token", "malformed", "zones", "list"}, nil, &stdout, &stderr); code != exitAuth {
+		t.Fatalf("expected exit code %d, got %d", exitAuth, code)
+	}
+}
+
+func Test_outputRecords(t *testing.T) {
+	records := []libdns.Record{
+		libdns.TXT{Name: "www", Text: `say "hi"`, TTL: time.Hour},
+	}
+	testCases := []struct {
+		format string
+		want   string
+	}{
+		{format: "table", want: "NAME  TTL     TYPE  DATA\nwww   1h0m0s  TXT   say \"hi\"\n"},
+		{format: "zonefile", want: "$ORIGIN example.com.\n$TTL 3600\nwww\t3600\tIN\tTXT\t\"say \\\"hi\\\"\"\n"},
+		{format: "json", want: `"data": "say \"hi\""`},
+	}
+	for _, c := range testCases {
+		var buf bytes.Buffer
+		out, err := newOutput(c.format, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := out.records("example.com", records); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), c.want) {
+			t.Errorf("%s: expected output to contain %q, got %q", c.format, c.want, buf.String())
+		}
+	}
+}
+
+func Test_runAgainstServer(t *testing.T) {
+	var posted int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "prefix.secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /dns/v1/zones":
+			fmt.Fprint(w, `[{"name":"example.com","id":"z1","type":"NATIVE"}]`)
+		case "GET /dns/v1/zones/z1":
+			fmt.Fprint(w, `{"name":"example.com","id":"z1","type":"NATIVE","records":[]}`)
+		case "POST /dns/v1/zones/z1/records":
+			posted++
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `[{"code":"INVALID_RECORD","message":"Record is invalid."}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("LIBDNS_IONOS_TOKEN", "prefix.secret")
+	t.Setenv(endpointEnv, srv.URL+"/dns/v1")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"zones", "list"}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("zones list: expected exit code %d, got %d (%s)", exitOK, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "example.com") {
+		t.Fatalf("zones list: expected example.com in output, got %q", stdout.String())
+	}
+
+	// the request rejected by IONOS is reported by the exit code
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"records", "append", "example.com", "www", "A", "1.2.3.4"}, nil, &stdout, &stderr); code != exitRejected {
+		t.Fatalf("records append: expected exit code %d, got %d (%s)", exitRejected, code, stderr.String())
+	}
+	if posted != 1 {
+		t.Fatalf("expected 1 POST request, got %d", posted)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/libdns/libdns"
+
+	"github.com/libdns/ionos"
+)
+
+// output writes zones and records in the format selected with -output.
+type output struct {
+	format string
+	w      io.Writer
+}
+
+func newOutput(format string, w io.Writer) (*output, error) {
+	switch format {
+	case "table", "json", "zonefile":
+		return &output{format: format, w: w}, nil
+	}
+	return nil, usageError{fmt.Sprintf("unknown output format %q", format)}
+}
+
+type jsonZone struct {
+	Name string `json:"name"`
+}
+
+type jsonRecord struct {
+	Name string `json:"name"`
+	TTL  int64  `json:"ttl"` // seconds
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+func (o *output) zones(zones []libdns.Zone) error {
+	switch o.format {
+	case "json":
+		result := make([]jsonZone, len(zones))
+		for i, z := range zones {
+			result[i] = jsonZone{Name: z.Name}
+		}
+		return o.json(result)
+	case "zonefile":
+		return usageError{"output format zonefile is not supported for zones"}
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME")
+	for _, z := range zones {
+		fmt.Fprintln(tw, z.Name)
+	}
+	return tw.Flush()
+}
+
+func toJSONRecords(records []libdns.Record) []jsonRecord {
+	result := make([]jsonRecord, len(records))
+	for i, r := range records {
+		rr := r.RR()
+		result[i] = jsonRecord{Name: rr.Name, TTL: int64(rr.TTL.Seconds()), Type: rr.Type, Data: rr.Data}
+	}
+	return result
+}
+
+func (o *output) records(zone string, records []libdns.Record) error {
+	switch o.format {
+	case "json":
+		return o.json(toJSONRecords(records))
+	case "zonefile":
+		return ionos.WriteZoneFile(o.w, zone, records)
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTTL\tTYPE\tDATA")
+	for _, r := range records {
+		rr := r.RR()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", rr.Name, rr.TTL, rr.Type, rr.Data)
+	}
+	return tw.Flush()
+}
+
+func (o *output) importResult(zone string, result ionos.ImportResult) error {
+	if o.format == "json" {
+		return o.json(struct {
+			Created []jsonRecord `json:"created"`
+			Updated []jsonRecord `json:"updated"`
+			Deleted []jsonRecord `json:"deleted"`
+		}{toJSONRecords(result.Created), toJSONRecords(result.Updated), toJSONRecords(result.Deleted)})
+	}
+	if len(result.Deleted) > 0 {
+		fmt.Fprintf(o.w, "; deleted %d records\n", len(result.Deleted))
+		if err := o.records(zone, result.Deleted); err != nil {
+			return err
+		}
+	}
+	if len(result.Updated) > 0 {
+		fmt.Fprintf(o.w, "; updated %d records\n", len(result.Updated))
+		if err := o.records(zone, result.Updated); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(o.w, "; created %d records\n", len(result.Created))
+	return o.records(zone, result.Created)
+}
+
+func (o *output) json(v any) error {
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
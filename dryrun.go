@@ -0,0 +1,144 @@
+// dry-run mode for mutating API calls
+package ionos
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DryRunChange is a create, update or delete request which was skipped in
+// dry-run mode. Record fields are given as they would have been sent to
+// IONOS; for deletes they are those of the existing record.
+type DryRunChange struct {
+	Action SyncAction
+	ZoneID string
+	// RecordID is the ID of the updated or deleted record. Records which
+	// would have been created get a placeholder ID starting with
+	// "dry-run-", under which later changes of the same dry run refer to
+	// them.
+	RecordID string
+	// Name is the fully-qualified name, without trailing dot.
+	Name    string
+	Type    string
+	Content string
+	// TTL in seconds, 0 if the IONOS default would be used.
+	TTL  int
+	Prio int
+}
+
+// DryRunLog collects the changes skipped in dry-run mode. It is safe for
+// concurrent use.
+type DryRunLog struct {
+	mu      sync.Mutex
+	changes []DryRunChange
+	created map[string]record // by placeholder ID
+}
+
+// Changes returns the skipped changes in the order they were requested.
+func (l *DryRunLog) Changes() []DryRunChange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]DryRunChange(nil), l.changes...)
+}
+
+func (l *DryRunLog) add(c DryRunChange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.changes = append(l.changes, c)
+}
+
+// addCreate records the creation of r and returns its placeholder ID.
+func (l *DryRunLog) addCreate(zoneID string, r record) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.created == nil {
+		l.created = make(map[string]record)
+	}
+	id := "dry-run-" + strconv.Itoa(len(l.created)+1)
+	l.created[id] = r
+	l.changes = append(l.changes, newDryRunChange(SyncCreate, zoneID, id, r))
+	return id
+}
+
+// createdRecord returns the record created in this dry run with the given
+// placeholder ID.
+func (l *DryRunLog) createdRecord(id string) (record, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.created[id]
+	return r, ok
+}
+
+// updateCreated replaces the record created in this dry run with the given
+// placeholder ID by r. It reports false if there is no such record.
+func (l *DryRunLog) updateCreated(id string, r record) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.created[id]; !ok {
+		return false
+	}
+	l.created[id] = r
+	return true
+}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context which puts all Provider methods called with
+// it into dry-run mode: read requests are made as usual, but POST, PUT and
+// DELETE requests are skipped and recorded in the returned log instead.
+// The methods return the records that would have been affected. Records
+// that would have been created have a placeholder ID, see DryRunChange, and
+// the TTL IONOS would apply.
+func WithDryRun(ctx context.Context) (context.Context, *DryRunLog) {
+	log := &DryRunLog{}
+	return context.WithValue(ctx, dryRunKey{}, log), log
+}
+
+// dryRunLog returns the log of ctx, or nil if ctx is not in dry-run mode.
+func dryRunLog(ctx context.Context) *DryRunLog {
+	log, _ := ctx.Value(dryRunKey{}).(*DryRunLog)
+	return log
+}
+
+// IsDryRun reports whether ctx was created by WithDryRun.
+func IsDryRun(ctx context.Context) bool {
+	return dryRunLog(ctx) != nil
+}
+
+func newDryRunChange(action SyncAction, zoneID, id string, r record) DryRunChange {
+	c := DryRunChange{
+		Action:   action,
+		ZoneID:   zoneID,
+		RecordID: id,
+		Name:     r.Name,
+		Type:     r.Type,
+		Content:  r.Content,
+		Prio:     r.Prio,
+	}
+	if r.TTL != nil {
+		c.TTL = *r.TTL
+	}
+	return c
+}
+
+// dryRunCreateRecords records the creation of records and returns them as
+// IONOS would, with a placeholder ID: with the default TTL of 1 hour
+// applied, and TXT content quoted.
+func dryRunCreateRecords(log *DryRunLog, zoneID string, records []record) []zoneRecord {
+	result := make([]zoneRecord, len(records))
+	for i, r := range records {
+		id := log.addCreate(zoneID, r)
+
+		zr := zoneRecord{ID: id, Name: r.Name, Type: r.Type, Content: r.Content, TTL: ionosDefaultTTL, Prio: r.Prio, Disabled: r.Disabled}
+		if r.TTL != nil {
+			zr.TTL = *r.TTL
+		}
+		if r.Type == "TXT" && !strings.HasPrefix(r.Content, `"`) {
+			zr.Content = strconv.Quote(r.Content)
+		}
+		result[i] = zr
+	}
+	return result
+}
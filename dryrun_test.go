@@ -0,0 +1,111 @@
+package ionos
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_DryRun(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	existing := fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+	obsolete := fs.addRecord(zone, "old", "TXT", "bye", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	ctx, log := WithDryRun(context.TODO())
+	if !IsDryRun(ctx) {
+		t.Fatal("expected context to be in dry-run mode")
+	}
+
+	appended, err := p.AppendRecords(ctx, "example.com", []libdns.Record{
+		libdns.TXT{Name: "new", Text: "hello"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := libdns.TXT{Name: "new", Text: "hello", TTL: time.Hour}
+	if len(appended) != 1 || appended[0] != want {
+		t.Fatalf("expected AppendRecords to return %+v, got %+v", want, appended)
+	}
+
+	set, err := p.SetRecords(ctx, "example.com", []libdns.Record{
+		libdns.Address{Name: "www", IP: netip.MustParseAddr("1.2.3.5"), TTL: time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 1 {
+		t.Fatalf("expected SetRecords to return 1 record, got %d", len(set))
+	}
+
+	deleted, err := p.DeleteRecords(ctx, "example.com", []libdns.Record{
+		libdns.TXT{Name: "old"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0].RR().Data != "bye" {
+		t.Fatalf("expected DeleteRecords to return the record to be deleted, got %+v", deleted)
+	}
+
+	for _, method := range []string{"POST", "PUT", "DELETE"} {
+		if n := fs.requestCount(method); n != 0 {
+			t.Errorf("expected no %s requests in dry-run mode, got %d", method, n)
+		}
+	}
+	if records := fs.records(zone); len(records) != 2 {
+		t.Fatalf("expected zone to be unchanged, got %+v", records)
+	}
+
+	changes := log.Changes()
+	wantChanges := []DryRunChange{
+		{Action: SyncCreate, ZoneID: zone.id, RecordID: "dry-run-1", Name: "new.example.com", Type: "TXT", Content: "hello"},
+		{Action: SyncUpdate, ZoneID: zone.id, RecordID: existing.ID, Name: "www.example.com", Type: "A", Content: "1.2.3.5", TTL: 60},
+		{Action: SyncDelete, ZoneID: zone.id, RecordID: obsolete.ID, Name: "old.example.com", Type: "TXT", Content: `"bye"`, TTL: 300},
+	}
+	if len(changes) != len(wantChanges) {
+		t.Fatalf("expected %d changes in log, got %+v", len(wantChanges), changes)
+	}
+	for i := range changes {
+		if changes[i] != wantChanges[i] {
+			t.Errorf("change %d: expected %+v, got %+v", i, wantChanges[i], changes[i])
+		}
+	}
+}
+
+func Test_DryRunUpdateOfCreatedRecord(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+
+	// the second record updates the one created for the first
+	p := &Provider{AuthAPIToken: "public.secret"}
+	ctx, log := WithDryRun(context.TODO())
+	if _, err := p.SetRecords(ctx, "example.com", []libdns.Record{
+		libdns.TXT{Name: "new", Text: "one", TTL: time.Hour},
+		libdns.TXT{Name: "new", Text: "two", TTL: time.Hour},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	changes := log.Changes()
+	if len(changes) != 2 || changes[0].Action != SyncCreate || changes[1].Action != SyncUpdate ||
+		changes[1].RecordID != changes[0].RecordID || changes[1].Content != "two" {
+		t.Fatalf("expected the created record to be updated, got %+v", changes)
+	}
+	if n := fs.requestCount("POST") + fs.requestCount("PUT"); n != 0 || len(fs.records(zone)) != 0 {
+		t.Fatalf("expected no changes in dry-run mode, got %d requests", n)
+	}
+}
+
+func Test_DryRunDeleteOfMissingRecordFails(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+
+	ctx, _ := WithDryRun(context.TODO())
+	if err := ionosDeleteRecord(ctx, apiClient{token: "public.secret"}, zone.id, "does-not-exist"); err == nil {
+		t.Fatal("expected error for missing record")
+	}
+}
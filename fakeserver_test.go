@@ -0,0 +1,331 @@
+package ionos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer is an in-memory implementation of the parts of the IONOS DNS
+// API used by this package. Each zone belongs to the account identified by
+// an API key.
+type fakeServer struct {
+	t *testing.T
+
+	mu        sync.Mutex
+	zones     map[string]*fakeZone // by zone ID
+	nextID    int
+	requests  map[string]int // number of requests by HTTP method
+	latency   time.Duration  // delay of each request
+	throttle  int            // number of next requests answered with 429
+	throttled int            // number of requests answered with 429
+
+	inFlight    int32 // requests currently handled, updated atomically
+	maxInFlight int32 // maximum of inFlight, updated atomically
+}
+
+type fakeZone struct {
+	id      string
+	name    string
+	token   string
+	records map[string]zoneRecord // by record ID
+}
+
+// newFakeServer starts a fake IONOS API and points the client to it for the
+// duration of the test.
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	fs := &fakeServer{
+		t:        t,
+		zones:    map[string]*fakeZone{},
+		requests: map[string]int{},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(fs.handle))
+	t.Cleanup(srv.Close)
+
+	old := apiEndpoint
+	apiEndpoint = srv.URL + "/dns/v1"
+	t.Cleanup(func() { apiEndpoint = old })
+	return fs
+}
+
+func (fs *fakeServer) newID() string {
+	fs.nextID++
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", fs.nextID)
+}
+
+// addZone creates an empty zone owned by the account with the given key.
+func (fs *fakeServer) addZone(token, name string) *fakeZone {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	z := &fakeZone{id: fs.newID(), name: name, token: token, records: map[string]zoneRecord{}}
+	fs.zones[z.id] = z
+	return z
+}
+
+// addRecord adds a record to the zone, bypassing the API. name is relative
+// to the zone.
+func (fs *fakeServer) addRecord(z *fakeZone, name, typ, content string, ttl int) zoneRecord {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.insertRecord(z, record{Name: absName(name, z.name), Type: typ, Content: content, TTL: &ttl})
+}
+
+// renameRecord changes the stored name of a record, bypassing the API, e.g.
+// to mimic names stored by IONOS in mixed case.
+func (fs *fakeServer) renameRecord(z *fakeZone, id, name string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	r := z.records[id]
+	r.Name = name
+	z.records[id] = r
+}
+
+// records returns all records of the zone, sorted by name, type and content.
+func (fs *fakeServer) records(z *fakeZone) []zoneRecord {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	result := make([]zoneRecord, 0, len(z.records))
+	for _, r := range z.records {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Content < b.Content
+	})
+	return result
+}
+
+// requestCount returns the number of requests received with the given
+// HTTP method.
+func (fs *fakeServer) requestCount(method string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.requests[method]
+}
+
+func absName(name, zone string) string {
+	if name == "" || name == "@" {
+		return zone
+	}
+	return name + "." + zone
+}
+
+// ionosContent mimics IONOS, which returns the content of TXT records in
+// quotes, regardless of whether it was quoted on creation.
+func ionosContent(typ, content string) string {
+	if typ == "TXT" && !strings.HasPrefix(content, `"`) {
+		return strconv.Quote(content)
+	}
+	return content
+}
+
+func (fs *fakeServer) insertRecord(z *fakeZone, r record) zoneRecord {
+	ttl := 3600
+	if r.TTL != nil {
+		ttl = *r.TTL
+	}
+	zr := zoneRecord{
+		ID:         fs.newID(),
+		Name:       strings.ToLower(r.Name),
+		RootName:   z.name,
+		Type:       r.Type,
+		Content:    ionosContent(r.Type, r.Content),
+		ChangeDate: time.Now().UTC().Format(time.RFC3339Nano),
+		TTL:        ttl,
+		Prio:       r.Prio,
+		Disabled:   r.Disabled,
+	}
+	z.records[zr.ID] = zr
+	return zr
+}
+
+func (fs *fakeServer) writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode([]map[string]string{{"code": code, "message": message}})
+}
+
+func (fs *fakeServer) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fs.t.Errorf("fake server: encode response: %v", err)
+	}
+}
+
+// setLatency delays all following requests by d, so that concurrent
+// requests overlap.
+func (fs *fakeServer) setLatency(d time.Duration) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.latency = d
+}
+
+// throttleNext answers the next n requests with 429 Too Many Requests and
+// a Retry-After header of 0 seconds.
+func (fs *fakeServer) throttleNext(n int) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.throttle = n
+}
+
+// throttledRequests returns the number of requests answered with 429.
+func (fs *fakeServer) throttledRequests() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.throttled
+}
+
+// maxConcurrentRequests returns the maximum number of requests handled at
+// the same time.
+func (fs *fakeServer) maxConcurrentRequests() int {
+	return int(atomic.LoadInt32(&fs.maxInFlight))
+}
+
+func (fs *fakeServer) handle(w http.ResponseWriter, req *http.Request) {
+	n := atomic.AddInt32(&fs.inFlight, 1)
+	defer atomic.AddInt32(&fs.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&fs.maxInFlight)
+		if n <= max || atomic.CompareAndSwapInt32(&fs.maxInFlight, max, n) {
+			break
+		}
+	}
+	fs.mu.Lock()
+	latency := fs.latency
+	fs.mu.Unlock()
+	time.Sleep(latency)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.throttle > 0 {
+		fs.throttle--
+		fs.throttled++
+		w.Header().Set("Retry-After", "0")
+		fs.writeError(w, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", "Rate limit exceeded.")
+		return
+	}
+	fs.requests[req.Method]++
+
+	token := req.Header.Get("X-API-Key")
+	known := false
+	for _, z := range fs.zones {
+		if z.token == token {
+			known = true
+			break
+		}
+	}
+	if !known {
+		fs.writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Missing or invalid API key.")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, "/dns/v1"), "/"), "/")
+	if parts[0] != "zones" {
+		fs.writeError(w, http.StatusNotFound, "NOT_FOUND", "Unknown resource.")
+		return
+	}
+
+	if len(parts) == 1 && req.Method == http.MethodGet {
+		zones := []zoneDescriptor{}
+		for _, z := range fs.zones {
+			if z.token == token {
+				zones = append(zones, zoneDescriptor{ID: z.id, Name: z.name, Type: "NATIVE"})
+			}
+		}
+		sort.Slice(zones, func(i, j int) bool { return zones[i].Name < zones[j].Name })
+		fs.writeJSON(w, zones)
+		return
+	}
+
+	z, ok := fs.zones[parts[1]]
+	if !ok || z.token != token {
+		fs.writeError(w, http.StatusNotFound, "ZONE_NOT_FOUND", "Zone not found.")
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && req.Method == http.MethodGet:
+		fs.getZone(w, req, z)
+	case len(parts) == 3 && parts[2] == "records" && req.Method == http.MethodPost:
+		var records []record
+		if err := json.NewDecoder(req.Body).Decode(&records); err != nil {
+			fs.writeError(w, http.StatusBadRequest, "INVALID_RECORD", err.Error())
+			return
+		}
+		created := make([]zoneRecord, len(records))
+		for i, r := range records {
+			created[i] = fs.insertRecord(z, r)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fs.writeJSON(w, created)
+	case len(parts) == 4 && parts[2] == "records":
+		fs.handleRecord(w, req, z, parts[3])
+	default:
+		fs.writeError(w, http.StatusNotFound, "NOT_FOUND", "Unknown resource.")
+	}
+}
+
+func (fs *fakeServer) getZone(w http.ResponseWriter, req *http.Request, z *fakeZone) {
+	q := req.URL.Query()
+	resp := getZoneResponse{ID: z.id, Name: z.name, Type: "NATIVE", Records: []zoneRecord{}}
+	for _, r := range z.records {
+		if typ := q.Get("recordType"); typ != "" && r.Type != typ {
+			continue
+		}
+		if name := q.Get("recordName"); name != "" && !strings.EqualFold(r.Name, name) {
+			continue
+		}
+		if suffix := q.Get("suffix"); suffix != "" && !strings.HasSuffix(strings.ToLower(r.Name), strings.ToLower(suffix)) {
+			continue
+		}
+		resp.Records = append(resp.Records, r)
+	}
+	sort.Slice(resp.Records, func(i, j int) bool { return resp.Records[i].ID < resp.Records[j].ID })
+	fs.writeJSON(w, resp)
+}
+
+func (fs *fakeServer) handleRecord(w http.ResponseWriter, req *http.Request, z *fakeZone, id string) {
+	existing, ok := z.records[id]
+	if !ok {
+		fs.writeError(w, http.StatusNotFound, "RECORD_NOT_FOUND", "Record not found.")
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		fs.writeJSON(w, existing)
+	case http.MethodDelete:
+		delete(z.records, id)
+	case http.MethodPut:
+		var r record
+		if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+			fs.writeError(w, http.StatusBadRequest, "INVALID_RECORD", err.Error())
+			return
+		}
+		existing.Content = ionosContent(existing.Type, r.Content)
+		existing.Prio = r.Prio
+		existing.Disabled = r.Disabled
+		if r.TTL != nil {
+			existing.TTL = *r.TTL
+		}
+		existing.ChangeDate = time.Now().UTC().Format(time.RFC3339Nano)
+		z.records[id] = existing
+		fs.writeJSON(w, existing)
+	default:
+		fs.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed.")
+	}
+}
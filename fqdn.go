@@ -0,0 +1,157 @@
+// zone discovery and record operations on fully-qualified names
+package ionos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// FindZoneForName returns the name of the zone in the account which is the
+// longest suffix of the fully-qualified name fqdn, e.g. "b.example.co.uk"
+// for "_acme-challenge.a.b.example.co.uk" if the account has the zones
+// "example.co.uk" and "b.example.co.uk". A trailing dot on fqdn is optional.
+// The returned zone name has no trailing dot, and is in the form selected by
+// p.UnicodeNames.
+func (p *Provider) FindZoneForName(ctx context.Context, fqdn string) (string, error) {
+	zoneDes, err := p.findZoneForName(ctx, fqdn)
+	if err != nil {
+		return "", err
+	}
+	return p.outputName(zoneDes.Name), nil
+}
+
+// findZoneForName returns the zone which fqdn belongs to, see
+// FindZoneForName.
+func (p *Provider) findZoneForName(ctx context.Context, fqdn string) (zoneDescriptor, error) {
+	zones, err := ionosGetAllZones(ctx, p.client())
+	if err != nil {
+		return zoneDescriptor{}, fmt.Errorf("get all zones: %w", err)
+	}
+	zoneDes, ok := longestZoneSuffix(zones.Zones, fqdn)
+	if !ok {
+		return zoneDescriptor{}, fmt.Errorf("%w for name (%s)", ErrZoneNotFound, fqdn)
+	}
+	return zoneDes, nil
+}
+
+// longestZoneSuffix returns the zone whose name is the longest suffix of
+// fqdn.
+func longestZoneSuffix(zones []zoneDescriptor, fqdn string) (zoneDescriptor, bool) {
+	name := canonicalName(fqdn)
+	var best zoneDescriptor
+	for _, zone := range zones {
+		z := canonicalName(zone.Name)
+		if (name == z || strings.HasSuffix(name, "."+z)) && len(z) > len(canonicalName(best.Name)) {
+			best = zone
+		}
+	}
+	return best, best.Name != ""
+}
+
+// SplitFQDN splits the fully-qualified name fqdn into the zone it belongs to
+// (see FindZoneForName) and the name relative to that zone.
+func (p *Provider) SplitFQDN(ctx context.Context, fqdn string) (zone, name string, err error) {
+	zone, err = p.FindZoneForName(ctx, fqdn)
+	if err != nil {
+		return "", "", err
+	}
+	return zone, p.outputName(relativeName(fqdn, zone)), nil
+}
+
+// fqdnBatch holds the records of a call to one of the *FQDN methods
+// belonging to the same zone. Names are relative to the zone.
+type fqdnBatch struct {
+	zone    string
+	records []libdns.Record
+}
+
+// splitRecordsByZone groups records with fully-qualified names by zone and
+// makes their names relative to the zone, keeping the type of the records,
+// e.g. a [Record] with its ID. Batches are returned in the order of the
+// first record of each zone.
+func (p *Provider) splitRecordsByZone(ctx context.Context, records []libdns.Record) ([]fqdnBatch, error) {
+	zones, err := ionosGetAllZones(ctx, p.client())
+	if err != nil {
+		return nil, fmt.Errorf("get all zones: %w", err)
+	}
+
+	var batches []fqdnBatch
+	index := make(map[string]int) // zone name -> index in batches
+	for _, r := range records {
+		rr := r.RR()
+		zoneDes, ok := longestZoneSuffix(zones.Zones, rr.Name)
+		if !ok {
+			return nil, fmt.Errorf("%w for name (%s)", ErrZoneNotFound, rr.Name)
+		}
+		zone := zoneDes.Name
+		rel := modifyRR(r, func(rr *libdns.RR) { rr.Name = relativeName(rr.Name, zone) })
+
+		i, ok := index[zone]
+		if !ok {
+			i = len(batches)
+			index[zone] = i
+			batches = append(batches, fqdnBatch{zone: zone})
+		}
+		batches[i].records = append(batches[i].records, rel)
+	}
+	return batches, nil
+}
+
+// toFQDNRecords makes the names of the given records, which are relative to
+// zone, fully-qualified in the form selected by p.UnicodeNames, keeping the
+// type of the records.
+func (p *Provider) toFQDNRecords(records []libdns.Record, zone string) []libdns.Record {
+	result := make([]libdns.Record, len(records))
+	for i, r := range records {
+		result[i] = modifyRR(r, func(rr *libdns.RR) {
+			rr.Name = p.outputName(libdns.AbsoluteName(rr.Name, unFQDN(zone)+"."))
+		})
+	}
+	return result
+}
+
+// forEachZone splits records by zone and calls fn for each batch. The
+// records returned by fn are collected with fully-qualified names.
+func (p *Provider) forEachZone(
+	ctx context.Context,
+	records []libdns.Record,
+	fn func(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error),
+) ([]libdns.Record, error) {
+	batches, err := p.splitRecordsByZone(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []libdns.Record
+	for _, b := range batches {
+		res, err := fn(ctx, b.zone, b.records)
+		results = append(results, p.toFQDNRecords(res, b.zone)...)
+		if err != nil {
+			return results, fmt.Errorf("zone %s: %w", b.zone, err)
+		}
+	}
+	return results, nil
+}
+
+// AppendRecordsFQDN works like AppendRecords, but the names of the records
+// are fully-qualified and the zones are determined using FindZoneForName.
+// The records may belong to different zones. The returned records have
+// fully-qualified names.
+func (p *Provider) AppendRecordsFQDN(ctx context.Context, records []libdns.Record) ([]libdns.Record, error) {
+	return p.forEachZone(ctx, records, p.AppendRecords)
+}
+
+// SetRecordsFQDN works like SetRecords, but the names of the records are
+// fully-qualified. See AppendRecordsFQDN.
+func (p *Provider) SetRecordsFQDN(ctx context.Context, records []libdns.Record) ([]libdns.Record, error) {
+	return p.forEachZone(ctx, records, p.SetRecords)
+}
+
+// DeleteRecordsFQDN works like DeleteRecords, but the names of the records
+// are fully-qualified. See AppendRecordsFQDN.
+func (p *Provider) DeleteRecordsFQDN(ctx context.Context, records []libdns.Record) ([]libdns.Record, error) {
+	return p.forEachZone(ctx, records, p.DeleteRecords)
+}
@@ -0,0 +1,106 @@
+package ionos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_FindZoneForName(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("public.secret", "example.co.uk")
+	fs.addZone("public.secret", "b.example.co.uk")
+	fs.addZone("public.secret", "le.co.uk")
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	testCases := []struct {
+		fqdn string
+		zone string
+		name string
+	}{
+		{fqdn: "_acme-challenge.a.b.example.co.uk", zone: "b.example.co.uk", name: "_acme-challenge.a"},
+		{fqdn: "_acme-challenge.a.b.example.co.uk.", zone: "b.example.co.uk", name: "_acme-challenge.a"},
+		{fqdn: "www.example.co.uk", zone: "example.co.uk", name: "www"},
+		{fqdn: "WWW.Example.CO.UK.", zone: "example.co.uk", name: "www"},
+		{fqdn: "example.co.uk", zone: "example.co.uk", name: "@"},
+		{fqdn: "www.le.co.uk", zone: "le.co.uk", name: "www"},
+	}
+	for _, c := range testCases {
+		zone, name, err := p.SplitFQDN(context.TODO(), c.fqdn)
+		if err != nil {
+			t.Fatalf("SplitFQDN(%q): %v", c.fqdn, err)
+		}
+		if zone != c.zone || name != c.name {
+			t.Errorf("SplitFQDN(%q): expected (%q, %q), got (%q, %q)", c.fqdn, c.zone, c.name, zone, name)
+		}
+	}
+
+	for _, fqdn := range []string{"example.org", "co.uk", "xexample.co.uk"} {
+		if zone, err := p.FindZoneForName(context.TODO(), fqdn); err == nil {
+			t.Errorf("FindZoneForName(%q): expected error, got zone %q", fqdn, zone)
+		}
+	}
+}
+
+func Test_AppendRecordsFQDN(t *testing.T) {
+	fs := newFakeServer(t)
+	zoneA := fs.addZone("public.secret", "example.com")
+	zoneB := fs.addZone("public.secret", "sub.example.com")
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	records := []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge.www.sub.example.com.", Text: "token-1", TTL: time.Minute},
+		libdns.TXT{Name: "_acme-challenge.example.com.", Text: "token-2", TTL: time.Minute},
+	}
+	created, err := p.AppendRecordsFQDN(context.TODO(), records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 {
+		t.Fatalf("expected 2 records to be created, got %d", len(created))
+	}
+	for i, r := range created {
+		if r.RR() != records[i].RR() {
+			t.Errorf("expected record %+v, got %+v", records[i].RR(), r.RR())
+		}
+	}
+
+	if rs := fs.records(zoneA); len(rs) != 1 || rs[0].Name != "_acme-challenge.example.com" {
+		t.Errorf("unexpected records in zone %s: %+v", zoneA.name, rs)
+	}
+	if rs := fs.records(zoneB); len(rs) != 1 || rs[0].Name != "_acme-challenge.www.sub.example.com" {
+		t.Errorf("unexpected records in zone %s: %+v", zoneB.name, rs)
+	}
+
+	deleted, err := p.DeleteRecordsFQDN(context.TODO(), created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 2 || len(fs.records(zoneA)) != 0 || len(fs.records(zoneB)) != 0 {
+		t.Fatalf("expected all records to be deleted, got %+v", deleted)
+	}
+}
+
+func Test_DeleteRecordsFQDNByID(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "txt", "TXT", "one", 300)
+	two := fs.addRecord(zone, "txt", "TXT", "two", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	deleted, err := p.DeleteRecordsFQDN(context.TODO(), []libdns.Record{
+		Record{Record: libdns.TXT{Name: "txt.example.com."}, ID: two.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the record with the ID is deleted, and returned as a Record
+	if len(deleted) != 1 || len(fs.records(zone)) != 1 {
+		t.Fatalf("expected exactly one record to be deleted, got %+v", deleted)
+	}
+	if rec, ok := deleted[0].(Record); !ok || rec.ID != two.ID || rec.RR().Name != "txt.example.com." {
+		t.Fatalf("unexpected deleted record %+v", deleted[0])
+	}
+}
@@ -1,5 +1,17 @@
 module github.com/libdns/ionos
 
-go 1.18
+go 1.20
 
-require github.com/libdns/libdns v1.0.0-beta.1
+require (
+	github.com/libdns/libdns v1.0.0-beta.1
+	github.com/miekg/dns v1.1.62
+	golang.org/x/net v0.27.0
+)
+
+require (
+	golang.org/x/mod v0.18.0 // indirect
+	golang.org/x/sync v0.7.0 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	golang.org/x/tools v0.22.0 // indirect
+)
@@ -0,0 +1,120 @@
+// protection of records against destructive changes
+package ionos
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Guard protects records against destructive changes. All methods of
+// Provider which update or delete records consult it before making any
+// PUT or DELETE request. The zero Guard allows everything.
+type Guard struct {
+	// Protected lists the records which are never updated or deleted, nor
+	// created by SetRecords.
+	Protected []ProtectedRecord `json:"protected,omitempty"`
+
+	// MaxDeletions is the maximum number of records a single call may
+	// delete. Calls which would delete more records fail before deleting
+	// any. Zero means no limit.
+	MaxDeletions int `json:"max_deletions,omitempty"`
+}
+
+// ProtectedRecord selects records protected by a Guard.
+type ProtectedRecord struct {
+	// Name is the name of the records relative to the zone, "@" for the
+	// apex. Shell patterns as understood by path.Match are allowed, e.g.
+	// "_dmarc" or "*._domainkey". Note that "*" matches any sequence of
+	// characters, including dots, so "*" alone protects all records, the
+	// apex included. Escape it as `\*` to select the wildcard record "*".
+	// Labels without pattern characters may be given as U-labels, e.g.
+	// "*.bücher".
+	Name string `json:"name"`
+	// Type is the record type, e.g. "MX". Empty means all types.
+	Type string `json:"type,omitempty"`
+}
+
+// String returns r in the form "name type", e.g. "@ MX".
+func (r ProtectedRecord) String() string {
+	typ := r.Type
+	if typ == "" {
+		typ = "*"
+	}
+	return r.Name + " " + strings.ToUpper(typ)
+}
+
+// matches reports whether r selects records with the given name, relative
+// to the zone, and type.
+func (r ProtectedRecord) matches(name, typ string) bool {
+	if r.Type != "" && !strings.EqualFold(r.Type, typ) {
+		return false
+	}
+	pattern := canonicalPattern(r.Name)
+	if pattern == "" {
+		pattern = "@"
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// canonicalPattern returns the name pattern with its labels in canonical
+// form, like names in canonicalName. Labels with pattern characters are
+// only lowercased, so that their escapes are kept.
+func canonicalPattern(pattern string) string {
+	labels := strings.Split(pattern, ".")
+	for i, label := range labels {
+		if strings.ContainsAny(label, `*?[\`) {
+			labels[i] = strings.ToLower(label)
+		} else {
+			labels[i] = canonicalName(label)
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// GuardError is returned if a call would modify a protected record, or
+// delete more records than allowed by Guard.MaxDeletions. Nothing is
+// changed for the record, or the call, respectively.
+type GuardError struct {
+	Zone string
+
+	// Name, relative to Zone, and Type of the protected record, and the
+	// rule protecting it. Empty if MaxDeletions was exceeded.
+	Name string
+	Type string
+	Rule ProtectedRecord
+
+	// Deletions is the number of records the call would have deleted, if
+	// it exceeds MaxDeletions.
+	Deletions    int
+	MaxDeletions int
+}
+
+func (e *GuardError) Error() string {
+	if e.Deletions > 0 {
+		return fmt.Sprintf("zone %s: refusing to delete %d records, more than the allowed maximum of %d", e.Zone, e.Deletions, e.MaxDeletions)
+	}
+	return fmt.Sprintf("zone %s: record %s %s is protected by rule %q", e.Zone, e.Name, e.Type, e.Rule)
+}
+
+// checkChange returns a *GuardError if records with the given absolute name
+// and type must not be updated or deleted in zone.
+func (g Guard) checkChange(zone, name, typ string) error {
+	rel := relativeName(name, zone)
+	for _, rule := range g.Protected {
+		if rule.matches(rel, typ) {
+			return &GuardError{Zone: canonicalName(zone), Name: rel, Type: strings.ToUpper(typ), Rule: rule}
+		}
+	}
+	return nil
+}
+
+// checkDeletions returns a *GuardError if a call must not delete n records
+// of zone.
+func (g Guard) checkDeletions(zone string, n int) error {
+	if g.MaxDeletions > 0 && n > g.MaxDeletions {
+		return &GuardError{Zone: canonicalName(zone), Deletions: n, MaxDeletions: g.MaxDeletions}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_ProtectedRecordMatches(t *testing.T) {
+	for _, tc := range []struct {
+		rule      ProtectedRecord
+		name, typ string
+		expected  bool
+	}{
+		{ProtectedRecord{Name: "@", Type: "MX"}, "@", "MX", true},
+		{ProtectedRecord{Name: "@", Type: "mx"}, "@", "MX", true},
+		{ProtectedRecord{Name: "@", Type: "MX"}, "@", "TXT", false},
+		{ProtectedRecord{Name: "", Type: "NS"}, "@", "NS", true},
+		{ProtectedRecord{Name: "_dmarc"}, "_dmarc", "TXT", true},
+		{ProtectedRecord{Name: "_DMARC"}, "_dmarc", "CNAME", true},
+		{ProtectedRecord{Name: "_dmarc"}, "_dmarc.sub", "TXT", false},
+		{ProtectedRecord{Name: "*._domainkey", Type: "TXT"}, "s1._domainkey", "TXT", true},
+		{ProtectedRecord{Name: "*._domainkey", Type: "TXT"}, "_domainkey", "TXT", false},
+		{ProtectedRecord{Name: "*._domainkey", Type: "TXT"}, "a.b._domainkey", "TXT", true},
+		// "*" alone matches every name, an escaped "*" only the wildcard
+		{ProtectedRecord{Name: "*"}, "www", "A", true},
+		{ProtectedRecord{Name: "*"}, "@", "MX", true},
+		{ProtectedRecord{Name: `\*`}, "*", "A", true},
+		{ProtectedRecord{Name: `\*`}, "www", "A", false},
+		// U-labels match the A-labels of the names
+		{ProtectedRecord{Name: "Bücher"}, "xn--bcher-kva", "A", true},
+		{ProtectedRecord{Name: "*.bücher"}, "www.xn--bcher-kva", "A", true},
+	} {
+		if got := tc.rule.matches(tc.name, tc.typ); got != tc.expected {
+			t.Errorf("%v matches %s %s = %v, expected %v", tc.rule, tc.name, tc.typ, got, tc.expected)
+		}
+	}
+}
+
+// newGuardedZone returns a zone with mail records and a provider protecting
+// them.
+func newGuardedZone(t *testing.T) (*fakeServer, *fakeZone, *Provider) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "@", "MX", "10 mx1.example.net", 3600)
+	fs.addRecord(zone, "@", "MX", "20 mx2.example.net", 3600)
+	fs.addRecord(zone, "_dmarc", "TXT", "v=DMARC1; p=reject", 3600)
+	fs.addRecord(zone, "a", "A", "1.1.1.1", 3600)
+	fs.addRecord(zone, "b", "A", "2.2.2.2", 3600)
+	fs.addRecord(zone, "c", "A", "3.3.3.3", 3600)
+
+	p := &Provider{AuthAPIToken: "public.secret", Guard: Guard{
+		Protected: []ProtectedRecord{
+			{Name: "@", Type: "MX"},
+			{Name: "_dmarc"},
+		},
+		MaxDeletions: 2,
+	}}
+	return fs, zone, p
+}
+
+func Test_GuardDeleteRecords(t *testing.T) {
+	fs, zone, p := newGuardedZone(t)
+
+	for _, records := range [][]libdns.Record{
+		{libdns.RR{Name: "@", Type: "MX"}},
+		{libdns.RR{Name: "a", Type: "A"}, libdns.RR{Name: "_dmarc", Type: "TXT"}},
+	} {
+		_, err := p.DeleteRecords(context.TODO(), "example.com", records)
+		var gerr *GuardError
+		if !errors.As(err, &gerr) || gerr.Type == "" || gerr.Zone != "example.com" {
+			t.Fatalf("expected a *GuardError for a protected record, got %v", err)
+		}
+	}
+
+	_, err := p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.RR{Name: "a", Type: "A"},
+		libdns.RR{Name: "b", Type: "A"},
+		libdns.RR{Name: "c", Type: "A"},
+	})
+	var gerr *GuardError
+	if !errors.As(err, &gerr) || gerr.Deletions != 3 || gerr.MaxDeletions != 2 {
+		t.Fatalf("expected a *GuardError for too many deletions, got %v", err)
+	}
+	if n := fs.requestCount("DELETE"); n != 0 {
+		t.Fatalf("expected no DELETE requests, got %d", n)
+	}
+
+	deleted, err := p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.RR{Name: "a", Type: "A"},
+		libdns.RR{Name: "b", Type: "A"},
+	})
+	if err != nil || len(deleted) != 2 {
+		t.Fatalf("expected 2 records to be deleted, got %v, %v", deleted, err)
+	}
+	if n := len(fs.records(zone)); n != 4 {
+		t.Fatalf("expected 4 remaining records, got %d", n)
+	}
+}
+
+func Test_GuardDeleteByNameAndID(t *testing.T) {
+	fs, _, p := newGuardedZone(t)
+	records, err := p.GetRecordsDetailed(context.TODO(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dmarc Record
+	for _, r := range records {
+		if r.RR().Name == "_dmarc" {
+			dmarc = r
+		}
+	}
+
+	_, err = p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.RR{Name: "a", Type: "A"},
+		dmarc,
+	})
+	var berr *BatchError
+	if errors.As(err, &berr) {
+		t.Fatalf("expected the *GuardError before the batch, got %v", err)
+	}
+	var gerr *GuardError
+	if !errors.As(err, &gerr) || gerr.Name != "_dmarc" {
+		t.Fatalf("expected a *GuardError, got %v", err)
+	}
+	if n := fs.requestCount("DELETE"); n != 0 {
+		t.Fatalf("expected no DELETE requests, got %d", n)
+	}
+}
+
+func Test_GuardByID(t *testing.T) {
+	fs, _, p := newGuardedZone(t)
+	records, err := p.GetRecordsDetailed(context.TODO(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dmarc, a Record
+	for _, r := range records {
+		switch r.RR().Name {
+		case "_dmarc":
+			dmarc = r
+		case "a":
+			a = r
+		}
+	}
+
+	var gerr *GuardError
+	renamed := a
+	renamed.Record = libdns.RR{Name: "_dmarc", Type: "A", Data: "1.1.1.1", TTL: time.Hour}
+	if _, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{renamed}); !errors.As(err, &gerr) {
+		t.Fatalf("expected a *GuardError, got %v", err)
+	}
+	if _, err := p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{dmarc}); !errors.As(err, &gerr) {
+		t.Fatalf("expected a *GuardError, got %v", err)
+	}
+	if _, err := p.DeleteRecordByID(context.TODO(), "example.com", dmarc.ID); !errors.As(err, &gerr) {
+		t.Fatalf("expected a *GuardError, got %v", err)
+	}
+	if _, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{dmarc}); !errors.As(err, &gerr) {
+		t.Fatalf("expected a *GuardError, got %v", err)
+	}
+	update := libdns.TXT{Name: "_dmarc", Text: "v=DMARC1; p=none", TTL: time.Hour}
+	if _, err := p.UpdateRecordByID(context.TODO(), "example.com", dmarc.ID, update, time.Time{}); !errors.As(err, &gerr) {
+		t.Fatalf("expected a *GuardError, got %v", err)
+	}
+	if n := fs.requestCount("DELETE") + fs.requestCount("PUT"); n != 0 {
+		t.Fatalf("expected no DELETE or PUT requests, got %d", n)
+	}
+}
+
+func Test_GuardSetRecords(t *testing.T) {
+	fs, _, p := newGuardedZone(t)
+
+	_, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.TXT{Name: "_dmarc", Text: "v=DMARC1; p=none", TTL: time.Hour},
+	})
+	var gerr *GuardError
+	if !errors.As(err, &gerr) || gerr.Name != "_dmarc" || gerr.Rule.Name != "_dmarc" {
+		t.Fatalf("expected a *GuardError, got %v", err)
+	}
+	if n := fs.requestCount("PUT"); n != 0 {
+		t.Fatalf("expected no PUT requests, got %d", n)
+	}
+
+	// protected records are not created either
+	p.Guard.Protected = append(p.Guard.Protected, ProtectedRecord{Name: "_mta-sts", Type: "TXT"})
+	if _, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.RR{Name: "a", Type: "A", Data: "9.9.9.9", TTL: time.Hour},
+		libdns.TXT{Name: "_mta-sts", Text: "v=STSv1; id=1", TTL: time.Hour},
+	}); !errors.As(err, &gerr) || gerr.Name != "_mta-sts" {
+		t.Fatalf("expected a *GuardError, got %v", err)
+	}
+	if n := fs.requestCount("POST") + fs.requestCount("PUT"); n != 0 {
+		t.Fatalf("expected no POST or PUT requests, got %d", n)
+	}
+
+	// unprotected records are updated and created
+	if _, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.RR{Name: "a", Type: "A", Data: "9.9.9.9", TTL: time.Hour},
+		libdns.RR{Name: "_dmarc.sub", Type: "TXT", Data: "v=DMARC1; p=none", TTL: time.Hour},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_GuardSyncAndRestore(t *testing.T) {
+	fs, zone, p := newGuardedZone(t)
+	snap, err := p.Snapshot(context.TODO(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a plan created without ProtectedNames still respects the guard
+	plan, err := p.PlanSync(context.TODO(), "example.com", []libdns.Record{
+		libdns.RR{Name: "a", Type: "A", Data: "1.1.1.1", TTL: time.Hour},
+	}, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gerr *GuardError
+	if applied, err := p.ApplySync(context.TODO(), plan); !errors.As(err, &gerr) || len(applied) != 0 {
+		t.Fatalf("expected a *GuardError before any change, got %v, %v", applied, err)
+	}
+
+	// restoring a snapshot must not delete more than MaxDeletions records
+	for _, name := range []string{"d", "e", "f"} {
+		fs.addRecord(zone, name, "A", "4.4.4.4", 3600)
+	}
+	if changes, err := p.Restore(context.TODO(), "example.com", snap); !errors.As(err, &gerr) || gerr.Deletions != 3 || len(changes) != 0 {
+		t.Fatalf("expected a *GuardError before any change, got %v, %v", changes, err)
+	}
+	if n := fs.requestCount("DELETE") + fs.requestCount("PUT"); n != 0 {
+		t.Fatalf("expected no DELETE or PUT requests, got %d", n)
+	}
+}
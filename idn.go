@@ -0,0 +1,86 @@
+// internationalized domain names
+package ionos
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/libdns/libdns"
+	"golang.org/x/net/idna"
+)
+
+// idnaProfile converts between Unicode and A-labels as for DNS lookups, but
+// allows underscores and wildcards, e.g. in "_acme-challenge.bücher.de".
+var idnaProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false), idna.BidiRule())
+
+// toASCII returns name with all labels in A-label (punycode) form, as used
+// by IONOS. Names which are ASCII already, and names which can not be
+// converted, are returned unchanged; the latter are then rejected by IONOS.
+func toASCII(name string) string {
+	if isASCII(name) {
+		return name
+	}
+	ascii, err := idnaProfile.ToASCII(name)
+	if err != nil {
+		return name
+	}
+	return ascii
+}
+
+// toUnicode returns name with all A-labels converted to Unicode.
+func toUnicode(name string) string {
+	if !strings.Contains(strings.ToLower(name), "xn--") {
+		return name
+	}
+	unicode, err := idnaProfile.ToUnicode(name)
+	if err != nil {
+		return name
+	}
+	return unicode
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// outputName converts a zone or record name returned by IONOS to the form
+// selected by p.UnicodeNames.
+func (p *Provider) outputName(name string) string {
+	if p.UnicodeNames {
+		return toUnicode(name)
+	}
+	return toASCII(name)
+}
+
+// outputRecords converts the names of records with outputName.
+func (p *Provider) outputRecords(records []libdns.Record) []libdns.Record {
+	for i, r := range records {
+		records[i] = p.outputRecord(r)
+	}
+	return records
+}
+
+// outputDetailed converts the name of rec with outputName.
+func (p *Provider) outputDetailed(rec Record) Record {
+	if rec.Record == nil {
+		return rec
+	}
+	return p.outputRecord(rec).(Record)
+}
+
+// outputRecord converts the name of r with outputName.
+func (p *Provider) outputRecord(r libdns.Record) libdns.Record {
+	if r == nil {
+		return r
+	}
+	name := r.RR().Name
+	if converted := p.outputName(name); converted != name {
+		return modifyRR(r, func(rr *libdns.RR) { rr.Name = converted })
+	}
+	return r
+}
@@ -0,0 +1,141 @@
+package ionos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_IDNConversion(t *testing.T) {
+	for _, tc := range []struct {
+		unicode, ascii string
+	}{
+		{"bücher.de", "xn--bcher-kva.de"},
+		{"café.bücher.de.", "xn--caf-dma.xn--bcher-kva.de."},
+		{"_acme-challenge.bücher.de", "_acme-challenge.xn--bcher-kva.de"},
+		{"*.bücher.de", "*.xn--bcher-kva.de"},
+		{"www.example.com", "www.example.com"},
+	} {
+		if got := toASCII(tc.unicode); got != tc.ascii {
+			t.Errorf("toASCII(%q) = %q, expected %q", tc.unicode, got, tc.ascii)
+		}
+		if got := toUnicode(tc.ascii); got != tc.unicode {
+			t.Errorf("toUnicode(%q) = %q, expected %q", tc.ascii, got, tc.unicode)
+		}
+	}
+}
+
+func Test_IDNZone(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "xn--bcher-kva.de")
+	fs.addRecord(zone, "xn--caf-dma", "A", "1.2.3.4", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	for _, name := range []string{"bücher.de", "xn--bcher-kva.de."} {
+		records, err := p.GetRecords(context.TODO(), name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(records) != 1 || records[0].RR().Name != "xn--caf-dma" {
+			t.Fatalf("%s: unexpected records %+v", name, records)
+		}
+	}
+
+	appended, err := p.AppendRecords(context.TODO(), "bücher.de.", []libdns.Record{
+		libdns.RR{Name: "läden", Type: "TXT", Data: "hello", TTL: 5 * time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(appended) != 1 || appended[0].RR().Name != "xn--lden-loa" {
+		t.Fatalf("unexpected appended records %+v", appended)
+	}
+	found := false
+	for _, r := range fs.records(zone) {
+		found = found || r.Name == "xn--lden-loa.xn--bcher-kva.de"
+	}
+	if !found {
+		t.Fatalf("record not created with A-labels: %+v", fs.records(zone))
+	}
+}
+
+func Test_IDNUnicodeNames(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "xn--bcher-kva.de")
+	fs.addRecord(zone, "xn--caf-dma", "A", "1.2.3.4", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret", UnicodeNames: true}
+	zones, err := p.ListZones(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zones) != 1 || zones[0].Name != "bücher.de" {
+		t.Fatalf("unexpected zones %+v", zones)
+	}
+
+	records, err := p.GetRecords(context.TODO(), "bücher.de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].RR().Name != "café" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+
+	detailed, err := p.GetRecordsDetailed(context.TODO(), "xn--bcher-kva.de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detailed) != 1 || detailed[0].RR().Name != "café" || detailed[0].ID == "" {
+		t.Fatalf("unexpected records %+v", detailed)
+	}
+
+	set, err := p.SetRecords(context.TODO(), "bücher.de", []libdns.Record{
+		libdns.RR{Name: "café", Type: "A", Data: "5.6.7.8", TTL: 5 * time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 1 || set[0].RR().Name != "café" {
+		t.Fatalf("unexpected set records %+v", set)
+	}
+	if n := len(fs.records(zone)); n != 1 {
+		t.Fatalf("expected the record to be updated in place, got %d records", n)
+	}
+
+	deleted, err := p.DeleteRecords(context.TODO(), "bücher.de", []libdns.Record{
+		libdns.RR{Name: "xn--caf-dma", Type: "A"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0].RR().Name != "café" {
+		t.Fatalf("unexpected deleted records %+v", deleted)
+	}
+}
+
+func Test_IDNUnicodeNamesFQDN(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("public.secret", "xn--bcher-kva.de")
+
+	p := &Provider{AuthAPIToken: "public.secret", UnicodeNames: true}
+	appended, err := p.AppendRecordsFQDN(context.TODO(), []libdns.Record{
+		libdns.RR{Name: "www.bücher.de.", Type: "A", Data: "1.2.3.4", TTL: 5 * time.Minute},
+		libdns.RR{Name: "xn--bcher-kva.de.", Type: "TXT", Data: "hello", TTL: 5 * time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(appended) != 2 || appended[0].RR().Name != "www.bücher.de." || appended[1].RR().Name != "bücher.de." {
+		t.Fatalf("unexpected appended records %+v", appended)
+	}
+
+	zone, name, err := p.SplitFQDN(context.TODO(), "café.xn--bcher-kva.de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zone != "bücher.de" || name != "café" {
+		t.Fatalf("unexpected split %q, %q", zone, name)
+	}
+}
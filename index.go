@@ -0,0 +1,74 @@
+// per-batch record lookups
+package ionos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/libdns/libdns"
+)
+
+// recordIndex holds the records of a zone by name and type. It is filled
+// once per batch, so that DeleteRecords and SetRecords do not need a lookup
+// request per record. It is safe for concurrent use.
+type recordIndex struct {
+	mu    sync.Mutex
+	byKey map[string][]zoneRecord
+}
+
+// recordKey returns the key of records with the given absolute name and
+// type.
+func recordKey(name, typ string) string {
+	return canonicalName(name) + "/" + strings.ToUpper(typ)
+}
+
+// libdnsRecordKey returns the key of r in the zone.
+func libdnsRecordKey(r libdns.Record, zoneName string) string {
+	rr := r.RR()
+	return recordKey(libdns.AbsoluteName(rr.Name, zoneName), rr.Type)
+}
+
+// newRecordIndex fetches the records of the zone with the types of records,
+// with one request per distinct type. Records without type or name, and
+// records targeted by ID, are ignored.
+func (p *Provider) newRecordIndex(ctx context.Context, zoneDes zoneDescriptor, records []libdns.Record) (*recordIndex, error) {
+	idx := &recordIndex{byKey: make(map[string][]zoneRecord)}
+	fetched := make(map[string]bool)
+	for _, r := range records {
+		if _, ok := asRecord(r); ok {
+			continue
+		}
+		rr := r.RR()
+		typ := strings.ToUpper(rr.Type)
+		if typ == "" || rr.Name == "" || fetched[typ] {
+			continue
+		}
+		fetched[typ] = true
+
+		resp, err := ionosGetZone(ctx, p.client(), zoneDes.ID, typ, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("get %s records: %w", typ, err)
+		}
+		for _, zr := range resp.Records {
+			key := recordKey(zr.Name, zr.Type)
+			idx.byKey[key] = append(idx.byKey[key], zr)
+		}
+	}
+	return idx, nil
+}
+
+// lookup returns the records with the given key.
+func (idx *recordIndex) lookup(key string) []zoneRecord {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return append([]zoneRecord(nil), idx.byKey[key]...)
+}
+
+// set replaces the records with the given key.
+func (idx *recordIndex) set(key string, records []zoneRecord) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.byKey[key] = records
+}
@@ -0,0 +1,130 @@
+package ionos
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_DeleteRecordsFetchesOncePerType(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	var records []libdns.Record
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("r%d", i)
+		fs.addRecord(zone, name, "TXT", name, 300)
+		records = append(records, libdns.TXT{Name: name})
+	}
+	fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+	records = append(records, libdns.RR{Name: "www", Type: "A"}, libdns.TXT{Name: "missing"})
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	deleted, err := p.DeleteRecords(context.TODO(), "example.com", records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 11 {
+		t.Fatalf("expected 11 deleted records, got %d", len(deleted))
+	}
+	// one request for the zone list, one per record type
+	if n := fs.requestCount("GET"); n != 3 {
+		t.Errorf("expected 3 GET requests, got %d", n)
+	}
+	if n := fs.requestCount("DELETE"); n != 11 {
+		t.Errorf("expected 11 DELETE requests, got %d", n)
+	}
+}
+
+func Test_DeleteRecordsMatchesTTLAndValue(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "txt", "TXT", "one", 300)
+	fs.addRecord(zone, "txt", "TXT", "two", 300)
+	fs.addRecord(zone, "txt", "TXT", "three", 600)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	tests := []struct {
+		record libdns.Record
+		want   []string
+	}{
+		{libdns.TXT{Name: "txt", Text: "one", TTL: 10 * time.Minute}, nil},
+		{libdns.TXT{Name: "txt", Text: "one"}, []string{"one"}},
+		{libdns.TXT{Name: "txt", TTL: 5 * time.Minute}, []string{"two"}},
+		{libdns.TXT{Name: "txt"}, []string{"three"}},
+	}
+	for _, tt := range tests {
+		deleted, err := p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{tt.record})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range deleted {
+			got = append(got, r.RR().Data)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%+v: expected %v to be deleted, got %v", tt.record, tt.want, got)
+		}
+	}
+}
+
+func Test_DeleteRecordsMatchesTargetWithTrailingDot(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "www", "CNAME", "target.example.net", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	deleted, err := p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.CNAME{Name: "www", Target: "target.example.net."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || len(fs.records(zone)) != 0 {
+		t.Fatalf("expected the CNAME to be deleted, got %v", deleted)
+	}
+}
+
+func Test_DeleteRecordsComparesTXTExactly(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "txt", "TXT", "end with dot.", 300)
+
+	// only target names are compared without trailing dot
+	p := &Provider{AuthAPIToken: "public.secret"}
+	deleted, err := p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.TXT{Name: "txt", Text: "end with dot"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 0 || len(fs.records(zone)) != 1 {
+		t.Fatalf("expected no record to be deleted, got %v", deleted)
+	}
+}
+
+func Test_SetRecordsFetchesOncePerType(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "a", "TXT", "old", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	_, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.TXT{Name: "a", Text: "new"},
+		libdns.TXT{Name: "b", Text: "new"},
+		libdns.TXT{Name: "c", Text: "new"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for method, n := range map[string]int{"GET": 2, "PUT": 1, "POST": 2} {
+		if got := fs.requestCount(method); got != n {
+			t.Errorf("expected %d %s requests, got %d", n, method, got)
+		}
+	}
+	if records := fs.records(zone); len(records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", records)
+	}
+}
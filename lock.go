@@ -0,0 +1,85 @@
+// per-zone serialization of mutating operations
+package ionos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// zoneLocks serializes the mutating operations on a zone within this
+// process. Locks are keyed by the IONOS zone ID, so that they apply to all
+// Providers, whatever name or token they use for the zone.
+var zoneLocks = &lockRegistry{locks: make(map[string]*zoneLock)}
+
+// lockRegistry hands out a lock per key. Locks are removed when no longer
+// used, so the registry does not grow with the number of zones seen.
+type lockRegistry struct {
+	mu    sync.Mutex
+	locks map[string]*zoneLock
+}
+
+type zoneLock struct {
+	// ch holds a token while the lock is held. A channel is used instead
+	// of a mutex so that waiting can be cancelled.
+	ch   chan struct{}
+	refs int
+}
+
+// lock acquires the lock for key. It returns a function to release the
+// lock, or the error of ctx if ctx is done before the lock was acquired.
+func (r *lockRegistry) lock(ctx context.Context, key string) (func(), error) {
+	r.mu.Lock()
+	l, ok := r.locks[key]
+	if !ok {
+		l = &zoneLock{ch: make(chan struct{}, 1)}
+		r.locks[key] = l
+	}
+	l.refs++
+	r.mu.Unlock()
+
+	select {
+	case l.ch <- struct{}{}:
+		return func() {
+			<-l.ch
+			r.release(key, l)
+		}, nil
+	case <-ctx.Done():
+		r.release(key, l)
+		return nil, ctx.Err()
+	}
+}
+
+func (r *lockRegistry) release(key string, l *zoneLock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(r.locks, key)
+	}
+}
+
+// lockZone serializes mutating operations on the zone, within the process
+// and, if p.Locker is set, across processes. The operation must use the
+// returned context, which is cancelled if the lock of p.Locker is lost. The
+// returned function releases the lock.
+func (p *Provider) lockZone(ctx context.Context, zoneDes zoneDescriptor) (context.Context, func(), error) {
+	unlock, err := zoneLocks.lock(ctx, zoneDes.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.Locker == nil {
+		return ctx, unlock, nil
+	}
+
+	held, unlockShared, err := p.Locker.Lock(ctx, lockKey(zoneDes.ID))
+	if err != nil {
+		unlock()
+		return nil, nil, fmt.Errorf("lock zone %s: %w", zoneDes.Name, err)
+	}
+	return held, func() {
+		// an error leaves the lock to expire
+		_ = unlockShared()
+		unlock()
+	}, nil
+}
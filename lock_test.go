@@ -0,0 +1,80 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_ConcurrentSetRecordsDoesNotDuplicate(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.setLatency(5 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// separate providers, like separate Caddy apps in one process
+			p := &Provider{AuthAPIToken: "public.secret"}
+			_, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{
+				libdns.TXT{Name: "_acme-challenge", Text: fmt.Sprintf("value %d", i)},
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if records := fs.records(zone); len(records) != 1 {
+		t.Fatalf("expected exactly one record, got %+v", records)
+	}
+	if n := fs.requestCount("POST"); n != 1 {
+		t.Errorf("expected 1 POST request, got %d", n)
+	}
+}
+
+func Test_LockRegistry(t *testing.T) {
+	r := &lockRegistry{locks: make(map[string]*zoneLock)}
+	unlock, err := r.lock(context.TODO(), "zone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// other zones are not blocked
+	unlockOther, err := r.lock(context.TODO(), "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockOther()
+
+	// waiting for a held lock can be cancelled
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.lock(ctx, "zone"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	unlock()
+	unlock, err = r.lock(context.TODO(), "zone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+
+	if len(r.locks) != 0 {
+		t.Fatalf("expected unused locks to be removed, got %d", len(r.locks))
+	}
+}
@@ -0,0 +1,266 @@
+// distributed locks for multi-process coordination
+package ionos
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Locker coordinates the mutations of a zone across processes. If set on a
+// Provider, the lock of a zone is acquired in addition to the per-process
+// lock, around every operation which is serialized per zone.
+type Locker interface {
+	// Lock acquires the lock with the given key, waiting until it is
+	// available or ctx is done. It returns a context derived from ctx,
+	// which is cancelled with cause ErrLockLost if the lock is lost while
+	// held, and a function which releases the lock. The operation holding
+	// the lock must use the returned context.
+	Lock(ctx context.Context, key string) (held context.Context, unlock func() error, err error)
+}
+
+// ErrLockLost is the cause of the cancellation of the context of a lock
+// which was lost while held, e.g. because it could not be renewed before
+// it expired.
+var ErrLockLost = errors.New("lock lost")
+
+// lockKey returns the Locker key of a zone.
+func lockKey(zoneID string) string {
+	return "libdns-ionos-zone-" + zoneID
+}
+
+// Default timings of the lockers.
+const (
+	defaultLockTTL          = 2 * time.Minute
+	defaultLockPollInterval = 100 * time.Millisecond
+)
+
+// newLockToken returns a random value identifying the holder of a lock.
+func newLockToken() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate lock token: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// holdLock renews a lock acquired with the given lease until the returned
+// release function is called. renew extends the lease, and reports false if
+// the lock is no longer held. The returned context is cancelled with
+// ErrLockLost once renew reports false, or fails until the lease ran out.
+func holdLock(ctx context.Context, lease time.Duration, renew func(ctx context.Context) (bool, error)) (context.Context, func()) {
+	held, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		expires := time.Now().Add(lease)
+		for {
+			select {
+			case <-done:
+				return
+			case <-held.Done():
+				return
+			case <-ticker.C:
+			}
+			start := time.Now()
+			ok, err := renew(held)
+			switch {
+			case err == nil && ok:
+				expires = start.Add(lease)
+			case err == nil || !time.Now().Before(expires):
+				cancel(ErrLockLost)
+				return
+			}
+		}
+	}()
+	return held, func() {
+		close(done)
+		<-stopped
+		cancel(nil)
+	}
+}
+
+// waitForLock waits for the next attempt to acquire a lock.
+func waitForLock(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultLockPollInterval
+	}
+	t := time.NewTimer(interval)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// FileLocker is a Locker using lock files in Dir, which must be on a file
+// system shared by all processes. A lock file is created exclusively and
+// contains a random token of its holder, who touches it every third of
+// StaleAfter while holding the lock. Lock files not touched for StaleAfter
+// are considered left over by a crashed process and taken over.
+type FileLocker struct {
+	Dir string
+	// StaleAfter defaults to 2 minutes.
+	StaleAfter time.Duration
+	// PollInterval is the delay between attempts to acquire a held lock,
+	// 100ms by default.
+	PollInterval time.Duration
+}
+
+func (l *FileLocker) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || key == "." || key == ".." {
+		return "", fmt.Errorf("invalid lock key %q", key)
+	}
+	return filepath.Join(l.Dir, key+".lock"), nil
+}
+
+// Lock creates the lock file of key, waiting while another holder has it.
+func (l *FileLocker) Lock(ctx context.Context, key string) (context.Context, func() error, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	token, err := newLockToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	staleAfter := l.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = defaultLockTTL
+	}
+
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			_, werr := f.WriteString(token)
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(path)
+				return nil, nil, fmt.Errorf("write lock file: %w", werr)
+			}
+			held, release := holdLock(ctx, staleAfter, func(context.Context) (bool, error) {
+				return l.renew(path, token)
+			})
+			return held, func() error {
+				release()
+				return l.unlock(path, token)
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, nil, fmt.Errorf("create lock file: %w", err)
+		}
+
+		if err := l.removeStale(path, token, staleAfter); err != nil {
+			return nil, nil, err
+		}
+		if err := waitForLock(ctx, l.PollInterval); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// readLockFile returns the token in the lock file at path, and whether it
+// was not touched for staleAfter.
+func readLockFile(path string, staleAfter time.Duration) (data []byte, stale bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	data, err = io.ReadAll(f)
+	return data, time.Since(info.ModTime()) > staleAfter, err
+}
+
+// removeStale removes the lock file at path if it is stale. To not remove
+// a lock file which was just renewed or replaced by a new holder, the file
+// is first renamed to a name unique to this attempt, and checked again
+// there. If it turns out to be live, it is put back, unless a new lock file
+// was created in the meantime; its holder then finds the lock lost when
+// renewing it.
+func (l *FileLocker) removeStale(path, token string, staleAfter time.Duration) error {
+	data, stale, err := readLockFile(path, staleAfter)
+	if errors.Is(err, os.ErrNotExist) || err == nil && !stale {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read lock file: %w", err)
+	}
+
+	moved := path + "." + token + ".stale"
+	if err := os.Rename(path, moved); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("remove stale lock file: %w", err)
+	}
+	defer os.Remove(moved)
+	movedData, stale, err := readLockFile(moved, staleAfter)
+	if err != nil || !stale || !bytes.Equal(movedData, data) {
+		// put back the live lock file, without replacing a new one
+		_ = os.Link(moved, path)
+	}
+	if err != nil {
+		return fmt.Errorf("read lock file: %w", err)
+	}
+	return nil
+}
+
+// renew touches the lock file, and reports whether it still holds token.
+func (l *FileLocker) renew(path, token string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(data, []byte(token)) {
+		return false, nil
+	}
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// unlock removes the lock file, unless it was taken over by another holder
+// after being considered stale.
+func (l *FileLocker) unlock(path, token string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read lock file: %w", err)
+	}
+	if !bytes.Equal(data, []byte(token)) {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove lock file: %w", err)
+	}
+	return nil
+}
+
+// Interface guard
+var _ Locker = (*FileLocker)(nil)
@@ -0,0 +1,195 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// testLocker checks that a Locker provides mutual exclusion.
+func testLocker(t *testing.T, l Locker) {
+	t.Helper()
+	var mu sync.Mutex
+	holders, maxHolders := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, unlock, err := l.Lock(context.TODO(), "key")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			holders++
+			if holders > maxHolders {
+				maxHolders = holders
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			if err := unlock(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxHolders != 1 {
+		t.Fatalf("expected one holder at a time, got %d", maxHolders)
+	}
+
+	// waiting can be cancelled
+	_, unlock, err := l.Lock(context.TODO(), "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := l.Lock(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_FileLocker(t *testing.T) {
+	testLocker(t, &FileLocker{Dir: t.TempDir(), PollInterval: time.Millisecond})
+}
+
+func Test_FileLockerStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	l := &FileLocker{Dir: dir, StaleAfter: time.Minute, PollInterval: time.Millisecond}
+
+	// a lock file left over by a crashed process
+	path := filepath.Join(dir, "key.lock")
+	if err := os.WriteFile(path, []byte("crashed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	_, unlock, err := l.Lock(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("expected only the new lock file, got %v", entries)
+	}
+
+	// a holder whose lock was taken over does not remove the new lock
+	if err := os.WriteFile(path, []byte("other"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected lock file of other holder to be kept: %v", err)
+	}
+
+	if _, _, err := l.Lock(ctx, "../key"); err == nil {
+		t.Fatal("expected error for invalid key")
+	}
+}
+
+// recordingLocker records the keys locked.
+type recordingLocker struct {
+	mu     sync.Mutex
+	locked []string
+	held   int
+}
+
+func (l *recordingLocker) Lock(ctx context.Context, key string) (context.Context, func() error, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.locked = append(l.locked, key)
+	l.held++
+	return ctx, func() error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.held--
+		return nil
+	}, nil
+}
+
+func Test_ProviderUsesLocker(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+
+	locker := &recordingLocker{}
+	p := &Provider{AuthAPIToken: "public.secret", Locker: locker}
+	if _, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.TXT{Name: "txt", Text: "value"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.TXT{Name: "txt"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := lockKey(zone.id)
+	if len(locker.locked) != 2 || locker.locked[0] != want || locker.locked[1] != want {
+		t.Fatalf("expected zone lock %s to be taken twice, got %v", want, locker.locked)
+	}
+	if locker.held != 0 {
+		t.Fatalf("expected all locks to be released, %d held", locker.held)
+	}
+}
+
+// testLockRenewal checks that a lock is held beyond its lease while
+// renewed, and that its context is cancelled once it is lost. steal
+// replaces the lock by that of another holder.
+func testLockRenewal(t *testing.T, l Locker, lease time.Duration, steal func()) {
+	t.Helper()
+	held, unlock, err := l.Lock(context.TODO(), "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 3*lease)
+	defer cancel()
+	if _, _, err := l.Lock(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the renewed lock to be held, got %v", err)
+	}
+	if held.Err() != nil {
+		t.Fatalf("expected the lock not to be lost, got %v", context.Cause(held))
+	}
+
+	steal()
+	select {
+	case <-held.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the context of the lost lock to be cancelled")
+	}
+	if err := context.Cause(held); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+}
+
+func Test_FileLockerRenewal(t *testing.T) {
+	dir := t.TempDir()
+	l := &FileLocker{Dir: dir, StaleAfter: 30 * time.Millisecond, PollInterval: time.Millisecond}
+	testLockRenewal(t, l, l.StaleAfter, func() {
+		if err := os.WriteFile(filepath.Join(dir, "key.lock"), []byte("other"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+// routing of libdns calls to multiple IONOS accounts
+package ionos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/libdns/libdns"
+)
+
+// MultiProvider implements the libdns interfaces for zones spread over
+// several IONOS accounts. Each call is routed to the provider owning the
+// zone. Zone ownership is found by calling ListZones on each provider. The
+// result is cached and refreshed when a zone is not found in the cache.
+type MultiProvider struct {
+	// Providers holds one Provider per IONOS account.
+	Providers []*Provider `json:"providers"`
+
+	mu     sync.Mutex
+	owners map[string]*Provider // by zone name, without trailing dot
+}
+
+// Refresh reloads the zones of all accounts and rebuilds the ownership
+// cache. It returns the zones of all accounts, in the order of Providers.
+// If a zone is visible to more than one account, the first account wins.
+func (m *MultiProvider) Refresh(ctx context.Context) ([]libdns.Zone, error) {
+	owners := make(map[string]*Provider)
+	var zones []libdns.Zone
+	for i, p := range m.Providers {
+		pz, err := p.ListZones(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list zones of provider %d: %w", i, err)
+		}
+		for _, z := range pz {
+			name := canonicalName(z.Name)
+			if _, ok := owners[name]; ok {
+				continue
+			}
+			owners[name] = p
+			zones = append(zones, z)
+		}
+	}
+
+	m.mu.Lock()
+	m.owners = owners
+	m.mu.Unlock()
+	return zones, nil
+}
+
+// providerFor returns the provider owning the given zone, refreshing the
+// ownership cache if the zone is not yet known.
+func (m *MultiProvider) providerFor(ctx context.Context, zone string) (*Provider, error) {
+	name := canonicalName(zone)
+
+	m.mu.Lock()
+	p, ok := m.owners[name]
+	m.mu.Unlock()
+	if ok {
+		return p, nil
+	}
+
+	if _, err := m.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	p, ok = m.owners[name]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w in any account (%s)", ErrZoneNotFound, zone)
+	}
+	return p, nil
+}
+
+// GetRecords lists all the records in the zone.
+func (m *MultiProvider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	p, err := m.providerFor(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetRecords(ctx, zone)
+}
+
+// AppendRecords adds records to the zone. It returns the records that were added.
+func (m *MultiProvider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	p, err := m.providerFor(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	return p.AppendRecords(ctx, zone, records)
+}
+
+// SetRecords sets the records in the zone, either by updating existing records
+// or creating new ones. It returns the updated records.
+func (m *MultiProvider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	p, err := m.providerFor(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	return p.SetRecords(ctx, zone, records)
+}
+
+// DeleteRecords deletes the records from the zone. It returns the records
+// that were deleted. See Provider.DeleteRecords for details.
+func (m *MultiProvider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	p, err := m.providerFor(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	return p.DeleteRecords(ctx, zone, records)
+}
+
+// ListZones returns the zones of all accounts. The ownership cache is
+// refreshed as a side effect.
+func (m *MultiProvider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+	zones, err := m.Refresh(ctx)
+	if err != nil {
+		return []libdns.Zone{}, err
+	}
+	return zones, nil
+}
+
+// Interface guards
+var (
+	_ libdns.RecordGetter   = (*MultiProvider)(nil)
+	_ libdns.RecordAppender = (*MultiProvider)(nil)
+	_ libdns.RecordSetter   = (*MultiProvider)(nil)
+	_ libdns.RecordDeleter  = (*MultiProvider)(nil)
+	_ libdns.ZoneLister     = (*MultiProvider)(nil)
+)
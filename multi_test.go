@@ -0,0 +1,70 @@
+package ionos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_MultiProviderRoutesByZone(t *testing.T) {
+	fs := newFakeServer(t)
+	zoneA := fs.addZone("a.secret", "example.com")
+	zoneB := fs.addZone("b.secret", "example.org")
+
+	m := &MultiProvider{Providers: []*Provider{
+		{AuthAPIToken: "a.secret"},
+		{AuthAPIToken: "b.secret"},
+	}}
+
+	records := []libdns.Record{libdns.TXT{Name: "test", Text: "hello", TTL: time.Minute}}
+	if _, err := m.AppendRecords(context.TODO(), "example.org.", records); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fs.records(zoneA)); n != 0 {
+		t.Fatalf("expected no records in zone of account a, got %d", n)
+	}
+	if n := len(fs.records(zoneB)); n != 1 {
+		t.Fatalf("expected 1 record in zone of account b, got %d", n)
+	}
+
+	// ownership is cached, only the first call lists the zones of all accounts
+	listCalls := fs.requestCount("GET")
+	if _, err := m.GetRecords(context.TODO(), "example.org"); err != nil {
+		t.Fatal(err)
+	}
+	// GetRecords itself needs two calls: find the zone and get the records
+	if n := fs.requestCount("GET") - listCalls; n != 2 {
+		t.Fatalf("expected 2 requests with cached ownership, got %d", n)
+	}
+
+	if _, err := m.GetRecords(context.TODO(), "example.net"); err == nil {
+		t.Fatal("expected error for unknown zone")
+	}
+}
+
+func Test_MultiProviderListZones(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("a.secret", "example.com")
+	fs.addZone("b.secret", "example.org")
+	fs.addZone("b.secret", "example.net")
+
+	m := &MultiProvider{Providers: []*Provider{
+		{AuthAPIToken: "a.secret"},
+		{AuthAPIToken: "b.secret"},
+	}}
+	zones, err := m.ListZones(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"example.com", "example.net", "example.org"}
+	if len(zones) != len(want) {
+		t.Fatalf("expected %d zones, got %+v", len(want), zones)
+	}
+	for i, z := range zones {
+		if z.Name != want[i] {
+			t.Fatalf("expected zone %d to be %s, got %s", i, want[i], z.Name)
+		}
+	}
+}
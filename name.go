@@ -0,0 +1,94 @@
+// canonical zone and record names
+package ionos
+
+import (
+	"strings"
+)
+
+// canonicalName returns name in the form used by IONOS, in which names are
+// compared: lowercase, with A-labels, and without trailing dot. Escapes of
+// the zone file presentation format ("\065", "\-") are resolved, except for
+// dots, backslashes and characters which are not printable, which remain
+// escaped.
+func canonicalName(name string) string {
+	name = unescapeName(name)
+	if strings.HasSuffix(name, ".") && !isEscaped(name, len(name)-1) {
+		name = name[:len(name)-1]
+	}
+	return strings.ToLower(toASCII(name))
+}
+
+// relativeName returns the canonical form of the absolute name fqdn relative
+// to zone, "@" for the apex. A trailing dot is optional on both. If fqdn is
+// not in zone, its canonical absolute form is returned.
+func relativeName(fqdn, zone string) string {
+	name, zone := canonicalName(fqdn), canonicalName(zone)
+	switch {
+	case name == zone:
+		return "@"
+	case zone == "":
+		return name
+	case strings.HasSuffix(name, "."+zone):
+		return strings.TrimSuffix(name, "."+zone)
+	}
+	return name
+}
+
+// inZone reports whether name is the apex of zone or below it. A trailing
+// dot is optional on both.
+func inZone(name, zone string) bool {
+	name, zone = canonicalName(name), canonicalName(zone)
+	return zone == "" || name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+// sameName reports whether a and b are the same name after
+// canonicalization.
+func sameName(a, b string) bool {
+	return canonicalName(a) == canonicalName(b)
+}
+
+// unescapeName resolves the escapes in name which canonicalName resolves.
+func unescapeName(name string) string {
+	if !strings.Contains(name, `\`) {
+		return name
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '\\' || i+1 == len(name) {
+			b.WriteByte(name[i])
+			continue
+		}
+		c, n := name[i+1], 1
+		if i+3 < len(name) && isDigit(name[i+1]) && isDigit(name[i+2]) && isDigit(name[i+3]) {
+			v := int(name[i+1]-'0')*100 + int(name[i+2]-'0')*10 + int(name[i+3]-'0')
+			if v > 255 {
+				b.WriteByte(name[i])
+				continue
+			}
+			c, n = byte(v), 3
+		}
+		if c == '.' || c == '\\' || c <= ' ' || c >= 0x7f {
+			// keep escaped, normalized to the shortest form
+			if c == '.' || c == '\\' {
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			} else {
+				b.WriteString(name[i : i+1+n])
+			}
+		} else {
+			b.WriteByte(c)
+		}
+		i += n
+	}
+	return b.String()
+}
+
+// isEscaped reports whether the byte at position i of name is escaped by a
+// backslash.
+func isEscaped(name string, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && name[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
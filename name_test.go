@@ -0,0 +1,102 @@
+package ionos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_canonicalName(t *testing.T) {
+	for _, tc := range []struct {
+		in, expected string
+	}{
+		{"example.com", "example.com"},
+		{"Example.COM.", "example.com"},
+		{"WWW.Example.Com", "www.example.com"},
+		{`\065bc.example.com.`, "abc.example.com"},
+		{`\-x.example.com`, "-x.example.com"},
+		{`a\.b.example.com`, `a\.b.example.com`},
+		{`a\046b.example.com.`, `a\.b.example.com`},
+		{`a\.`, `a\.`},
+		{`a\\.`, `a\\`},
+		{`tab\009.example.com`, `tab\009.example.com`},
+		{"Bücher.DE.", "xn--bcher-kva.de"},
+		{"*.Example.com", "*.example.com"},
+		{"", ""},
+	} {
+		if got := canonicalName(tc.in); got != tc.expected {
+			t.Errorf("canonicalName(%q) = %q, expected %q", tc.in, got, tc.expected)
+		}
+	}
+}
+
+func Test_relativeName(t *testing.T) {
+	for _, tc := range []struct {
+		fqdn, zone, expected string
+	}{
+		{"www.example.com", "example.com", "www"},
+		{"WWW.Example.COM.", "example.com.", "www"},
+		{"example.com", "Example.COM.", "@"},
+		{"a.b.example.com.", "example.com", "a.b"},
+		{"notexample.com", "example.com", "notexample.com"},
+		{"www.other.org.", "example.com", "www.other.org"},
+	} {
+		if got := relativeName(tc.fqdn, tc.zone); got != tc.expected {
+			t.Errorf("relativeName(%q, %q) = %q, expected %q", tc.fqdn, tc.zone, got, tc.expected)
+		}
+	}
+}
+
+func Test_MixedCaseNames(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	www := fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+	fs.renameRecord(zone, www.ID, "WWW.Example.com")
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	records, err := p.GetRecords(context.TODO(), "Example.COM.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].RR().Name != "www" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+
+	// a record differing only in case is updated, not duplicated
+	if _, err := p.SetRecords(context.TODO(), "EXAMPLE.com", []libdns.Record{
+		libdns.RR{Name: "Www", Type: "A", Data: "5.6.7.8", TTL: 5 * time.Minute},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	stored := fs.records(zone)
+	if len(stored) != 1 || stored[0].ID != www.ID || stored[0].Content != "5.6.7.8" {
+		t.Fatalf("unexpected records %+v", stored)
+	}
+
+	deleted, err := p.DeleteRecords(context.TODO(), "example.com.", []libdns.Record{
+		libdns.RR{Name: "WWW", Type: "A"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || len(fs.records(zone)) != 0 {
+		t.Fatalf("expected the record to be deleted, got %+v", deleted)
+	}
+}
+
+func Test_FindZoneForNameMixedCase(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("public.secret", "example.com")
+	fs.addZone("public.secret", "Sub.Example.com")
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	zone, name, err := p.SplitFQDN(context.TODO(), "_ACME-Challenge.a.SUB.example.COM.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zone != "Sub.Example.com" || name != "_acme-challenge.a" {
+		t.Fatalf("unexpected split %q, %q", zone, name)
+	}
+}
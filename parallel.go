@@ -0,0 +1,188 @@
+// bounded-parallel processing of record batches
+package ionos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/libdns/libdns"
+)
+
+// RecordError is the error of a single record of a batch.
+type RecordError struct {
+	Record libdns.Record
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	rr := e.Record.RR()
+	return fmt.Sprintf("record %s %s: %v", rr.Name, rr.Type, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError is returned by DeleteRecords and SetRecords with
+// Provider.ContinueOnError if some records of the batch failed. The records
+// not listed were processed successfully.
+type BatchError struct {
+	// Errors are the errors of the failed records, in input order.
+	Errors []*RecordError
+}
+
+func (e *BatchError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d record(s) failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed records, so that errors.Is and
+// errors.As find e.g. an *APIError of any of them.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// concurrency returns the number of records processed in parallel.
+func (p *Provider) concurrency() int {
+	if p.Concurrency < 1 {
+		return 1
+	}
+	return p.Concurrency
+}
+
+// groupRecords groups the indices of records by key, in order of first
+// appearance. Records of the same group must be processed sequentially.
+func groupRecords(records []libdns.Record, key func(libdns.Record) string) [][]int {
+	var groups [][]int
+	byKey := make(map[string]int)
+	for i, r := range records {
+		k := key(r)
+		g, ok := byKey[k]
+		if !ok {
+			g = len(groups)
+			byKey[k] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+// processRecords calls fn for each record, processing up to p.Concurrency
+// groups in parallel, and the records of a group in order. It returns the
+// results in input order. Once ctx is done, the remaining records fail with
+// the error of ctx.
+//
+// By default, no further records are started after the first failure, and
+// the error of the first failed record in input order is returned. With
+// p.ContinueOnError, all records are processed, and a *BatchError lists
+// the records which failed.
+func (p *Provider) processRecords(
+	ctx context.Context,
+	records []libdns.Record,
+	groups [][]int,
+	fn func(ctx context.Context, r libdns.Record) ([]libdns.Record, error),
+) ([]libdns.Record, error) {
+	results := make([][]libdns.Record, len(records))
+	errs := make([]error, len(records))
+
+	// stop is closed after the first failure, unless p.ContinueOnError
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	failed := func() {
+		if !p.ContinueOnError {
+			stopOnce.Do(func() { close(stop) })
+		}
+	}
+	stopped := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return false
+		}
+	}
+
+	workers := p.concurrency()
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+	queue := make(chan []int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range queue {
+				for _, i := range group {
+					if stopped() {
+						break
+					}
+					if err := ctx.Err(); err != nil {
+						errs[i] = err
+						failed()
+						continue
+					}
+					results[i], errs[i] = fn(ctx, records[i])
+					if errs[i] != nil {
+						failed()
+					}
+				}
+			}
+		}()
+	}
+	started := 0
+dispatch:
+	for _, group := range groups {
+		select {
+		case queue <- group:
+			started++
+		case <-ctx.Done():
+			break dispatch
+		case <-stop:
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+	if !stopped() {
+		for _, group := range groups[started:] {
+			for _, i := range group {
+				errs[i] = ctx.Err()
+			}
+		}
+	}
+
+	var flat []libdns.Record
+	if !p.ContinueOnError {
+		for i := range records {
+			flat = append(flat, results[i]...)
+		}
+		for _, err := range errs {
+			if err != nil {
+				return flat, err
+			}
+		}
+		return flat, nil
+	}
+	var batchErr BatchError
+	for i := range records {
+		flat = append(flat, results[i]...)
+		if errs[i] != nil {
+			batchErr.Errors = append(batchErr.Errors, &RecordError{Record: records[i], Err: errs[i]})
+		}
+	}
+	if len(batchErr.Errors) > 0 {
+		return flat, &batchErr
+	}
+	return flat, nil
+}
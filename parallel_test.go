@@ -0,0 +1,133 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_ParallelDeleteRecords(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	var records []libdns.Record
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("r%02d", i)
+		fs.addRecord(zone, name, "TXT", name, 300)
+		records = append(records, libdns.TXT{Name: name})
+	}
+	fs.setLatency(10 * time.Millisecond)
+
+	p := &Provider{AuthAPIToken: "public.secret", Concurrency: 4, ContinueOnError: true}
+	deleted, err := p.DeleteRecords(context.TODO(), "example.com", records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != len(records) {
+		t.Fatalf("expected %d deleted records, got %d", len(records), len(deleted))
+	}
+	for i, r := range deleted {
+		if want := records[i].RR().Name; r.RR().Name != want {
+			t.Errorf("record %d: expected %s, got %s", i, want, r.RR().Name)
+		}
+	}
+	if n := len(fs.records(zone)); n != 0 {
+		t.Fatalf("expected empty zone, got %d records", n)
+	}
+	if n := fs.maxConcurrentRequests(); n < 2 || n > 4 {
+		t.Errorf("expected 2 to 4 concurrent requests, got %d", n)
+	}
+}
+
+func Test_ParallelSetRecordsAggregatesErrors(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("public.secret", "example.com")
+
+	p := &Provider{AuthAPIToken: "public.secret", Concurrency: 3, ContinueOnError: true}
+	records := []libdns.Record{
+		libdns.TXT{Name: "one", Text: "1"},
+		Record{Record: libdns.TXT{Name: "missing", Text: "x"}, ID: "does-not-exist"},
+		libdns.TXT{Name: "two", Text: "2"},
+	}
+	set, err := p.SetRecords(context.TODO(), "example.com", records)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected BatchError, got %v", err)
+	}
+	if len(batchErr.Errors) != 1 || batchErr.Errors[0].Record != records[1] {
+		t.Fatalf("expected error for the second record only, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected wrapped 404 APIError, got %v", err)
+	}
+	if len(set) != 2 || set[0].RR().Name != "one" || set[1].RR().Name != "two" {
+		t.Fatalf("expected the other records to be set in order, got %+v", set)
+	}
+}
+
+func Test_ParallelCancel(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	var records []libdns.Record
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("r%02d", i)
+		fs.addRecord(zone, name, "TXT", name, 300)
+		records = append(records, libdns.TXT{Name: name})
+	}
+
+	// cancel while the first records are being processed
+	p := &Provider{AuthAPIToken: "public.secret", Concurrency: 2, ContinueOnError: true}
+	zoneDes, err := p.findZoneByName(context.TODO(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := p.newRecordIndex(context.TODO(), zoneDes, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	groups := groupRecords(records, func(r libdns.Record) string { return r.RR().Name })
+	deleted, err := p.processRecords(ctx, records, groups, func(ctx context.Context, r libdns.Record) ([]libdns.Record, error) {
+		cancel()
+		return p.deleteRecord(ctx, zoneDes, idx, r)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors)+len(deleted) != len(records) {
+		t.Fatalf("expected every record to be either deleted or failed, got %d deleted and %v", len(deleted), err)
+	}
+	if n := len(fs.records(zone)); n < len(records)-2 {
+		t.Fatalf("expected at most 2 records to be deleted after cancel, %d left", n)
+	}
+}
+
+func Test_ParallelStopsAtFirstError(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	var records []libdns.Record
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("r%02d", i)
+		fs.addRecord(zone, name, "TXT", name, 300)
+		records = append(records, libdns.TXT{Name: name})
+	}
+	records[1] = Record{Record: libdns.TXT{Name: "r01"}, ID: "does-not-exist"}
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	_, err := p.SetRecords(context.TODO(), "example.com", records)
+	var batchErr *BatchError
+	if err == nil || errors.As(err, &batchErr) {
+		t.Fatalf("expected the error of the failed record, got %v", err)
+	}
+	// r00 is updated, r01 is not found, and the remaining records are not
+	// touched
+	if n := fs.requestCount("PUT"); n != 1 {
+		t.Fatalf("expected 1 PUT request, got %d", n)
+	}
+}
@@ -5,6 +5,7 @@ package ionos
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -13,26 +14,99 @@ import (
 	"github.com/libdns/libdns"
 )
 
-// Provider implements the libdns interfaces for IONOS
+// ErrZoneNotFound is returned (wrapped) when a zone does not exist in the
+// account.
+var ErrZoneNotFound = errors.New("zone not found")
+
+// Provider implements the libdns interfaces for IONOS.
+//
+// Provider is safe for concurrent use. Within a process, SetRecords,
+// DeleteRecords and the other methods which read records before changing
+// them are serialized per zone, across all Providers, so that e.g. two
+// concurrent SetRecords for the same name never create two records.
+// AppendRecords is not serialized, as it does not depend on existing
+// records. Set Locker to extend the guarantee to several processes.
 type Provider struct {
 	// AuthAPIToken is the IONOS Auth API token -
 	// see https://dns.ionos.com/api-docs#section/Authentication/Auth-API-Token
 	AuthAPIToken string `json:"auth_api_token"`
+
+	// Concurrency is the number of records DeleteRecords and SetRecords
+	// process in parallel. Values below 1 mean 1.
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// ContinueOnError makes DeleteRecords and SetRecords process all
+	// records of a batch even if some fail, and return a *BatchError
+	// listing the failed ones. By default they stop at the first failure
+	// and return its error.
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
+
+	// RateLimit is the maximum number of requests per second sent with
+	// AuthAPIToken, shared by all Providers of this process using the
+	// token. Zero means no limit.
+	RateLimit float64 `json:"rate_limit,omitempty"`
+
+	// MaxRetries is the number of times a request answered with 429 Too
+	// Many Requests is retried, after the delay given by IONOS in the
+	// Retry-After header. Zero means 3, a negative value disables retries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// Locker, if set, serializes the operations on a zone across processes,
+	// e.g. a FileLocker or RedisLocker shared by all replicas.
+	Locker Locker `json:"-"`
+
+	// TTLPolicy normalizes the TTLs of records created or updated by any
+	// method, including sync, restore and UpdateRecordByID. By default, TTLs
+	// below 60 seconds are rejected before any change is made.
+	TTLPolicy TTLPolicy `json:"ttl_policy,omitempty"`
+
+	// UnicodeNames selects the form of internationalized zone and record
+	// names returned: Unicode ("bücher.de") if true, A-labels
+	// ("xn--bcher-kva.de") otherwise. Both forms are accepted as input.
+	UnicodeNames bool `json:"unicode_names,omitempty"`
+
+	// Guard protects records against updates and deletions, and limits the
+	// number of deletions per call. See Guard.
+	Guard Guard `json:"guard,omitempty"`
 }
 
 func toIonosRecord(r libdns.Record, zoneName string) record {
 	rr := r.RR()
-	return record{
+	content, prio := ionosPrio(rr)
+	result := record{
 		Type:    rr.Type,
-		Name:    libdns.AbsoluteName(rr.Name, zoneName),
-		Content: rr.Data,
+		Name:    canonicalName(libdns.AbsoluteName(rr.Name, zoneName)),
+		Content: content,
 		TTL:     ionosTTL(rr.TTL.Seconds()),
+		Prio:    prio,
+	}
+	if rec, ok := asRecord(r); ok {
+		result.Disabled = rec.Disabled
 	}
+	return result
+}
+
+// ionosPrio returns the content and priority of rr for the IONOS API,
+// which expects the preference of MX records in the prio field instead of
+// the content.
+func ionosPrio(rr libdns.RR) (string, int) {
+	if !strings.EqualFold(rr.Type, "MX") {
+		return rr.Data, 0
+	}
+	parsed, err := rr.Parse()
+	if err != nil {
+		return rr.Data, 0
+	}
+	mx, ok := parsed.(libdns.MX)
+	if !ok {
+		return rr.Data, 0
+	}
+	return mx.Target, int(mx.Preference)
 }
 
 func fromIonosRecord(r zoneRecord, zoneName string) (libdns.Record, error) {
 	// libdns Name is partially qualified, relative to zone, Ionos absoulte
-	name := libdns.RelativeName(r.Name, zoneName) // use r.rootName for zoneName TODO?
+	name := relativeName(r.Name, zoneName)
 	ttl := time.Duration(r.TTL) * time.Second
 
 	switch strings.ToUpper(r.Type) {
@@ -54,18 +128,18 @@ func fromIonosRecord(r zoneRecord, zoneName string) (libdns.Record, error) {
 
 func (p *Provider) findZoneByName(ctx context.Context, zoneName string) (zoneDescriptor, error) {
 	// obtain list of all zones
-	zones, err := ionosGetAllZones(ctx, p.AuthAPIToken)
+	zones, err := ionosGetAllZones(ctx, p.client())
 	if err != nil {
 		return zoneDescriptor{}, fmt.Errorf("get all zones: %w", err)
 	}
 
 	// find the desired zone
 	for _, zone := range zones.Zones {
-		if zone.Name == unFQDN(zoneName) {
+		if sameName(zone.Name, zoneName) {
 			return zone, nil
 		}
 	}
-	return zoneDescriptor{}, fmt.Errorf("zone named not found (%s)", zoneName)
+	return zoneDescriptor{}, fmt.Errorf("%w (%s)", ErrZoneNotFound, zoneName)
 }
 
 // GetRecords lists all the records in the zone.
@@ -76,40 +150,54 @@ func (p *Provider) GetRecords(ctx context.Context, zoneName string) ([]libdns.Re
 	}
 
 	// obtain list of all records in zone
-	zoneResp, err := ionosGetZone(ctx, p.AuthAPIToken, zoneDes.ID, "", "")
+	zoneResp, err := ionosGetZone(ctx, p.client(), zoneDes.ID, "", "", "")
 	if err != nil {
 		return nil, fmt.Errorf("get zone records: %w", err)
 	}
 
 	records := make([]libdns.Record, len(zoneResp.Records))
 	for i, r := range zoneResp.Records {
-		record, err := fromIonosRecord(r, zoneName)
+		record, err := fromIonosRecord(r, zoneDes.Name)
 		if err != nil {
 			return records, fmt.Errorf("convert record: %w", err)
 		}
 		records[i] = record
 	}
-	return records, nil
+	return p.outputRecords(records), nil
 }
 
 // AppendRecords adds records to the zone. It returns the records that were added.
+// Invalid records are rejected with a *ValidationError before any request is
+// made, see ValidateRecords.
 func (p *Provider) AppendRecords(
 	ctx context.Context,
 	zone string,
 	records []libdns.Record,
 ) ([]libdns.Record, error) {
+	records, err := p.applyTTLPolicy(records)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateRecords(zone, records); err != nil {
+		return nil, err
+	}
 	zoneDes, err := p.findZoneByName(ctx, zone)
 	if err != nil {
 		return nil, fmt.Errorf("find zone: %w", err)
 	}
+	return p.createRecords(ctx, zoneDes, records)
+}
 
+// createRecords creates records, which were validated already, in the zone
+// and returns them.
+func (p *Provider) createRecords(ctx context.Context, zoneDes zoneDescriptor, records []libdns.Record) ([]libdns.Record, error) {
 	// populate ionos request
 	reqs := make([]record, len(records))
 	for i, r := range records {
 		reqs[i] = toIonosRecord(r, zoneDes.Name)
 	}
 
-	newRecords, err := ionosCreateRecords(ctx, p.AuthAPIToken, zoneDes.ID, reqs)
+	newRecords, err := ionosCreateRecords(ctx, p.client(), zoneDes.ID, reqs)
 	if err != nil {
 		return nil, fmt.Errorf("create records: %w", err)
 	}
@@ -123,7 +211,7 @@ func (p *Provider) AppendRecords(
 		}
 		results[i] = result
 	}
-	return results, nil
+	return p.outputRecords(results), nil
 }
 
 // DeleteRecords deletes the given records from the zone if they exist in the
@@ -152,8 +240,18 @@ func (p *Provider) AppendRecords(
 // Implementations must honor context cancellation and be safe for concurrent
 // use.
 //
-// libdns-ionos notes: we use ionosFindRecordsInZone to filter the records,
-// which does not support TTL
+// libdns-ionos notes: the records of the types in the batch are fetched once
+// per type and matched locally. Records of type [Record] with an ID are
+// deleted by their ID. Up to p.Concurrency records are processed in
+// parallel. After the first failure, no further records are started, and
+// its error is returned together with the records that were deleted; with
+// p.ContinueOnError all records are processed, and a *BatchError lists the
+// failed ones.
+// The SOA and apex NS records are never deleted; if records match them,
+// ErrApexRecord is returned before anything is deleted. Records without
+// name or type match nothing. If p.Guard forbids deleting any of the
+// matched records, or as many, a *GuardError is returned before anything is
+// deleted.
 func (p *Provider) DeleteRecords(
 	ctx context.Context,
 	zone string,
@@ -163,99 +261,273 @@ func (p *Provider) DeleteRecords(
 	if err != nil {
 		return nil, fmt.Errorf("find zone: %w", err)
 	}
+	ctx, unlock, err := p.lockZone(ctx, zoneDes)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	idx, err := p.newRecordIndex(ctx, zoneDes, records)
+	if err != nil {
+		return nil, fmt.Errorf("find records for deletion: %w", err)
+	}
+	if err := p.checkDeleteRecords(ctx, zoneDes, idx, records); err != nil {
+		return nil, err
+	}
+
+	// ionos api has no batch-delete, delete one record at a time. Records
+	// which may match the same records are processed sequentially.
+	groups := groupRecords(records, func(r libdns.Record) string {
+		if rec, ok := asRecord(r); ok {
+			return "id:" + rec.ID
+		}
+		return libdnsRecordKey(r, zoneDes.Name)
+	})
+	deleted, err := p.processRecords(ctx, records, groups, func(ctx context.Context, r libdns.Record) ([]libdns.Record, error) {
+		return p.deleteRecord(ctx, zoneDes, idx, r)
+	})
+	return p.outputRecords(deleted), err
+}
 
-	// ionos api has no batch-delete, delete one record at a time
-	var deleteQueue []libdns.Record // list of record IDs to delete
+// deleteRecord deletes the records of the zone matching r and returns them.
+func (p *Provider) deleteRecord(ctx context.Context, zoneDes zoneDescriptor, idx *recordIndex, r libdns.Record) ([]libdns.Record, error) {
+	if rec, ok := asRecord(r); ok {
+		deleted, found, err := p.deleteRecordByID(ctx, zoneDes, rec.ID)
+		if err != nil || !found {
+			return nil, err
+		}
+		return []libdns.Record{deleted}, nil
+	}
+
+	rr := r.RR()
+	// safety: avoid deleting the whole zone
+	if rr.Type == "" || rr.Name == "" {
+		return nil, nil
+	}
 
+	key := libdnsRecordKey(r, zoneDes.Name)
+	var deleted []libdns.Record
+	var remaining []zoneRecord
+	existing := idx.lookup(key)
+	for i, found := range existing {
+		result, err := fromIonosRecord(found, zoneDes.Name)
+		if err != nil {
+			return deleted, fmt.Errorf("convert record: %w", err)
+		}
+		if !matchesDelete(rr, result.RR()) {
+			remaining = append(remaining, found)
+			continue
+		}
+		// a record deleted by ID in the same batch is gone already
+		if err := ionosDeleteRecord(ctx, p.client(), zoneDes.ID, found.ID); err != nil && !isNotFound(err) {
+			idx.set(key, append(remaining, existing[i:]...))
+			return deleted, fmt.Errorf("delete record %+v, %w", found, err)
+		}
+		deleted = append(deleted, result)
+	}
+	idx.set(key, remaining)
+	return deleted, nil
+}
+
+// matchesDelete reports whether the existing record found matches rr given
+// to DeleteRecords, as the libdns spec requires. TTL and value are only
+// compared if given; values are compared like in PlanSync, see syncData.
+func matchesDelete(rr, found libdns.RR) bool {
+	return (rr.TTL == 0 || rr.TTL == found.TTL) && (rr.Data == "" || syncData(rr) == syncData(found))
+}
+
+// checkDeleteRecords checks the records DeleteRecords would delete against
+// the apex rules and p.Guard, so that a batch violating them deletes
+// nothing. Records given with an ID are fetched for this.
+func (p *Provider) checkDeleteRecords(ctx context.Context, zoneDes zoneDescriptor, idx *recordIndex, records []libdns.Record) error {
+	ids := make(map[string]bool)
 	for _, r := range records {
+		if rec, ok := asRecord(r); ok {
+			if ids[rec.ID] {
+				continue
+			}
+			existing, err := p.getRecordByID(ctx, zoneDes, rec.ID)
+			if errors.Is(err, ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := checkApexDelete(existing.Name, existing.Type, zoneDes.Name); err != nil {
+				return err
+			}
+			if err := p.Guard.checkChange(zoneDes.Name, existing.Name, existing.Type); err != nil {
+				return err
+			}
+			ids[rec.ID] = true
+			continue
+		}
 		rr := r.RR()
-		// safety: avoid deleting the whole zone
 		if rr.Type == "" || rr.Name == "" {
 			continue
 		}
-
-		// search record first to obtain the record ID, which is needed to delete the record
-		name := libdns.AbsoluteName(rr.Name, zoneDes.Name)
-		existing, err := ionosFindRecordsInZone(ctx, p.AuthAPIToken, zoneDes.ID, rr.Type, name)
-		// TODO according to libdns spec, we need to also match for TTL and
-		// value of the record
-		if err != nil {
-			return nil, fmt.Errorf("find record for deletion: %w", err)
+		if err := checkApexDelete(libdns.AbsoluteName(rr.Name, zoneDes.Name), rr.Type, zoneDes.Name); err != nil {
+			return err
 		}
-		for _, found := range existing {
+		for _, found := range idx.lookup(libdnsRecordKey(r, zoneDes.Name)) {
 			result, err := fromIonosRecord(found, zoneDes.Name)
-			if err != nil {
-				return deleteQueue, fmt.Errorf("convert record: %w", err)
+			if err != nil || !matchesDelete(rr, result.RR()) {
+				continue
 			}
-			if err := ionosDeleteRecord(ctx, p.AuthAPIToken, zoneDes.ID, found.ID); err != nil {
-				return deleteQueue, fmt.Errorf("delete record %+v, %w", found, err)
+			if err := p.Guard.checkChange(zoneDes.Name, found.Name, found.Type); err != nil {
+				return err
 			}
-			deleteQueue = append(deleteQueue, result)
+			ids[found.ID] = true
 		}
 	}
+	return p.Guard.checkDeletions(zoneDes.Name, len(ids))
+}
 
-	return deleteQueue, nil
+// checkSetRecords checks the records SetRecords would create or update
+// against p.Guard, by their new name and type, and for records given with
+// an ID also by the name and type of the record they replace.
+func (p *Provider) checkSetRecords(ctx context.Context, zoneDes zoneDescriptor, records []libdns.Record) error {
+	if len(p.Guard.Protected) == 0 {
+		return nil
+	}
+	for _, r := range records {
+		rr := r.RR()
+		if err := p.Guard.checkChange(zoneDes.Name, libdns.AbsoluteName(rr.Name, zoneDes.Name), rr.Type); err != nil {
+			return err
+		}
+		rec, ok := asRecord(r)
+		if !ok {
+			continue
+		}
+		existing, err := p.getRecordByID(ctx, zoneDes, rec.ID)
+		if errors.Is(err, ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.Guard.checkChange(zoneDes.Name, existing.Name, existing.Type); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (p *Provider) createOrUpdateRecord(
 	ctx context.Context,
 	zoneDes zoneDescriptor,
+	idx *recordIndex,
 	r libdns.Record,
 ) (libdns.Record, error) {
-	rr := r.RR()
+	if rr := r.RR(); rr.Type != "" {
+		if err := checkApexSet(libdns.AbsoluteName(rr.Name, zoneDes.Name), rr.Type); err != nil {
+			return r, err
+		}
+	}
+
+	// records with an ID are updated in place
+	if rec, ok := asRecord(r); ok {
+		existing, err := p.getRecordByID(ctx, zoneDes, rec.ID)
+		if err != nil {
+			return r, err
+		}
+		if err := checkSameNameAndType(existing, r, zoneDes.Name); err != nil {
+			return r, err
+		}
+		if err := ionosUpdateRecord(ctx, p.client(), zoneDes.ID, rec.ID, toIonosRecord(r, zoneDes.Name)); err != nil {
+			return r, fmt.Errorf("update record %s: %w", rec.ID, err)
+		}
+		return r, nil
+	}
+
 	// before we create a new record, make sure there is no existing record
 	// of same (type, name). In this case we only update the record
-	name := libdns.AbsoluteName(rr.Name, zoneDes.Name)
-	existing, err := ionosFindRecordsInZone(ctx, p.AuthAPIToken, zoneDes.ID, rr.Type, name)
-	if err == nil {
+	key := libdnsRecordKey(r, zoneDes.Name)
+	if existing := idx.lookup(key); len(existing) > 0 {
 		if len(existing) != 1 {
-			return r, fmt.Errorf("unexpected number of records during delete, expected 1, found %d", len(existing))
+			return r, fmt.Errorf("unexpected number of records during update, expected 1, found %d", len(existing))
 		}
-		err := ionosUpdateRecord(ctx, p.AuthAPIToken, zoneDes.ID, existing[0].ID, toIonosRecord(r, zoneDes.Name))
+		err := ionosUpdateRecord(ctx, p.client(), zoneDes.ID, existing[0].ID, toIonosRecord(r, zoneDes.Name))
 		if err != nil {
 			return r, fmt.Errorf("update found record: %w", err)
 		}
+		// without TTL, IONOS keeps the TTL of the record
+		if r.RR().TTL == 0 {
+			return withTTL(r, time.Duration(existing[0].TTL)*time.Second), nil
+		}
 		return r, nil
 	}
 
-	created, err := ionosCreateRecords(ctx, p.AuthAPIToken, zoneDes.ID, []record{toIonosRecord(r, zoneDes.Name)})
+	created, err := ionosCreateRecords(ctx, p.client(), zoneDes.ID, []record{toIonosRecord(r, zoneDes.Name)})
 	if err != nil {
 		return r, fmt.Errorf("create new record: %w", err)
 	}
 	if len(created) != 1 {
 		return r, fmt.Errorf("expected one record to be created, got %d", len(created))
 	}
+	idx.set(key, created)
 	return fromIonosRecord(created[0], zoneDes.Name)
 }
 
 // SetRecords sets the records in the zone, either by updating existing records
-// or creating new ones. It returns the updated records.
+// or creating new ones. It returns the updated records. Records of type
+// [Record] with an ID update the record with that ID, which must have the
+// same name and type. SOA records can not be set, they fail with
+// ErrApexRecord.
+//
+// Invalid records are rejected with a *ValidationError before any request
+// is made, see ValidateRecords. If p.Guard protects any of the records, by
+// their new or their current name and type, a *GuardError is returned
+// before anything is changed. Up to p.Concurrency records are processed in
+// parallel. After the first failure, no further records are started, and
+// its error is returned together with the records that were set; with
+// p.ContinueOnError all records are processed, and a *BatchError lists the
+// failed ones.
 func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	var res []libdns.Record
-
+	records, err := p.applyTTLPolicy(records)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateRecords(zone, records); err != nil {
+		return nil, err
+	}
 	zoneDes, err := p.findZoneByName(ctx, zone)
 	if err != nil {
 		return nil, fmt.Errorf("find zone: %w", err)
 	}
+	ctx, unlock, err := p.lockZone(ctx, zoneDes)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	idx, err := p.newRecordIndex(ctx, zoneDes, records)
+	if err != nil {
+		return nil, fmt.Errorf("find existing records: %w", err)
+	}
+	if err := p.checkSetRecords(ctx, zoneDes, records); err != nil {
+		return nil, err
+	}
 
-	for _, r := range records {
-		newRecord, err := p.createOrUpdateRecord(ctx, zoneDes, r)
+	groups := groupRecords(records, func(r libdns.Record) string {
+		return libdnsRecordKey(r, zoneDes.Name)
+	})
+	set, err := p.processRecords(ctx, records, groups, func(ctx context.Context, r libdns.Record) ([]libdns.Record, error) {
+		newRecord, err := p.createOrUpdateRecord(ctx, zoneDes, idx, r)
 		if err != nil {
-			return res, err
+			return nil, err
 		}
-		res = append(res, newRecord)
-	}
-	return res, nil
+		return []libdns.Record{newRecord}, nil
+	})
+	return p.outputRecords(set), err
 }
 
 func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
-	zones, err := ionosGetAllZones(ctx, p.AuthAPIToken)
+	zones, err := ionosGetAllZones(ctx, p.client())
 	if err != nil {
 		return []libdns.Zone{}, fmt.Errorf("get all zones: %w", err)
 	}
 	result := make([]libdns.Zone, len(zones.Zones))
 	for i, zone := range zones.Zones {
-		result[i].Name = zone.Name
+		result[i].Name = p.outputName(zone.Name)
 	}
 	return result, nil
 }
@@ -1,10 +1,12 @@
+//go:build e2e
+
 // end-to-end test suite, using the original IONOS API service (i.e. no test
 // doubles - be careful). set environment variables
 //
 //	LIBDNS_IONOS_TEST_TOKEN - API token
 //	LIBDNS_IONOS_TEST_ZONE - domain
 //
-// before running the test.
+// before running the test with "go test -tags e2e".
 package ionos_test
 
 import (
@@ -255,11 +257,28 @@ func Test_GetRecords(t *testing.T) {
 		if found == nil {
 			t.Fatalf("Record %+v not found", r)
 		}
+	}
 
-		// TODO compare Records
-		//		if found.ID != r.ID {
-		//			t.Fatalf("Record found but ID differs (%s != %s)", r.ID, found.ID)
-		//		}
+	// the detailed records carry the IONOS IDs, which must be stable
+	detailed, err := p.GetRecordsDetailed(context.TODO(), envZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := p.GetRecordsDetailed(context.TODO(), envZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]string)
+	for _, r := range again {
+		ids[r.RR().Name+"/"+r.RR().Type+"/"+r.RR().Data] = r.ID
+	}
+	for _, r := range detailed {
+		if r.ID == "" {
+			t.Fatalf("Record %+v has no ID", r)
+		}
+		if id := ids[r.RR().Name+"/"+r.RR().Type+"/"+r.RR().Data]; id != r.ID {
+			t.Fatalf("Record found but ID differs (%s != %s)", r.ID, id)
+		}
 	}
 }
 
@@ -305,7 +324,7 @@ func TestMain(m *testing.M) {
 		fmt.Println(`Please notice that this test runs agains the public ionos DNS Api, so you sould
 never run the test with a zone, used in production.
 To run this test, you have to specify 'LIBDNS_IONOS_TEST_TOKEN' and 'LIBDNS_IONOS_TEST_ZONE'.
-Example: "LIBDNS_IONOS_TEST_TOKEN="123.456" LIBDNS_IONOS_TEST_ZONE="my-domain.com" go test ./... -v`)
+Example: "LIBDNS_IONOS_TEST_TOKEN="123.456" LIBDNS_IONOS_TEST_ZONE="my-domain.com" go test -tags e2e ./... -v`)
 		os.Exit(1)
 	}
 
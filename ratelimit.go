@@ -0,0 +1,160 @@
+// client-side rate limiting and retry of throttled requests
+package ionos
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultMaxRetries is the number of times a request answered with 429 Too
+// Many Requests is retried if Provider.MaxRetries is zero.
+const defaultMaxRetries = 3
+
+// maxRetryDelay caps the delay before retrying a throttled request.
+const maxRetryDelay = time.Minute
+
+// apiClient holds what the requests of a Provider need: the API token, and
+// the rate limiter shared by all requests made with the token.
+type apiClient struct {
+	token   string
+	limiter *rateLimiter
+	retries int
+}
+
+// client returns the apiClient for the requests of p.
+func (p *Provider) client() apiClient {
+	retries := p.MaxRetries
+	switch {
+	case retries == 0:
+		retries = defaultMaxRetries
+	case retries < 0:
+		retries = 0
+	}
+	return apiClient{
+		token:   p.AuthAPIToken,
+		limiter: rateLimiters.get(p.AuthAPIToken, p.RateLimit),
+		retries: retries,
+	}
+}
+
+// rateLimiters holds a limiter per API token. IONOS limits requests per
+// API key, so the limiter is shared by all Providers and workers of this
+// process using the same token.
+var rateLimiters = &limiterRegistry{limiters: make(map[string]*rateLimiter)}
+
+type limiterRegistry struct {
+	mu       sync.Mutex
+	limiters map[string]*rateLimiter
+}
+
+// get returns the limiter for token, with its rate set to rate requests
+// per second.
+func (r *limiterRegistry) get(token string, rate float64) *rateLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.limiters[token]
+	if !ok {
+		l = &rateLimiter{}
+		r.limiters[token] = l
+	}
+	l.setRate(rate)
+	return l
+}
+
+// rateLimiter is a token bucket allowing rate requests per second, with
+// bursts of up to one second's worth of requests. It also holds back all
+// requests while IONOS asked to pause with a 429 response.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // requests per second, 0 for no limit
+	tokens float64
+	last   time.Time
+	paused time.Time // no requests before this time
+}
+
+func (l *rateLimiter) setRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rate != l.rate {
+		l.rate = rate
+		l.tokens = l.burst()
+		l.last = time.Now()
+	}
+}
+
+func (l *rateLimiter) burst() float64 {
+	if l.rate < 1 {
+		return 1
+	}
+	return l.rate
+}
+
+// wait blocks until a request may be made, or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token and returns 0, or returns how long to wait before
+// trying again.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Before(l.paused) {
+		return l.paused.Sub(now)
+	}
+	if l.rate <= 0 {
+		return 0
+	}
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if b := l.burst(); l.tokens > b {
+		l.tokens = b
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// pause holds back all requests for d.
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.paused) {
+		l.paused = until
+	}
+}
+
+// retryDelay returns the delay before retry attempt n (starting at 0) of a
+// throttled request, taken from the Retry-After header if present.
+func retryDelay(header http.Header, n int) time.Duration {
+	d := time.Second << uint(n)
+	if v := header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			d = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(v); err == nil {
+			d = time.Until(t)
+		}
+	}
+	if d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return d
+}
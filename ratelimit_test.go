@@ -0,0 +1,93 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_RetryThrottledRequests(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("retry.secret", "example.com")
+
+	p := &Provider{AuthAPIToken: "retry.secret"}
+	fs.throttleNext(2)
+	created, err := p.AppendRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "1.2.3.4", TTL: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 1 || len(fs.records(zone)) != 1 || fs.throttledRequests() != 2 {
+		t.Fatalf("expected the record to be created after 2 retries, got %+v", created)
+	}
+
+	// the request body is sent again on retry
+	fs.throttleNext(1)
+	if _, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "5.6.7.8", TTL: time.Hour},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if r := fs.records(zone); len(r) != 1 || r[0].Content != "5.6.7.8" {
+		t.Fatalf("unexpected records %+v", r)
+	}
+
+	p.MaxRetries = -1
+	fs.throttleNext(1)
+	_, err = p.GetRecords(context.TODO(), "example.com")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected a 429 *APIError without retries, got %v", err)
+	}
+}
+
+func Test_RateLimitSharedByWorkers(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("ratelimit.secret", "example.com")
+
+	const rate = 10
+	p := &Provider{AuthAPIToken: "ratelimit.secret", Concurrency: 4, RateLimit: rate}
+	var records []libdns.Record
+	for i := 0; i < 15; i++ {
+		records = append(records, libdns.RR{Name: fmt.Sprintf("host%d", i), Type: "A", Data: "1.2.3.4", TTL: time.Hour})
+	}
+
+	start := time.Now()
+	if _, err := p.SetRecords(context.TODO(), "example.com", records); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+
+	// a burst of rate requests is allowed, the rest is spaced by 1/rate
+	total := fs.requestCount("GET") + fs.requestCount("POST") + fs.requestCount("PUT")
+	if min := time.Duration(total-rate) * time.Second / rate; elapsed < min {
+		t.Fatalf("%d requests took %v, expected at least %v", total, elapsed, min)
+	}
+}
+
+func Test_retryDelay(t *testing.T) {
+	for _, tc := range []struct {
+		header   string
+		attempt  int
+		expected time.Duration
+	}{
+		{"", 0, time.Second},
+		{"", 2, 4 * time.Second},
+		{"3", 0, 3 * time.Second},
+		{"3600", 0, maxRetryDelay},
+	} {
+		h := http.Header{}
+		if tc.header != "" {
+			h.Set("Retry-After", tc.header)
+		}
+		if got := retryDelay(h, tc.attempt); got != tc.expected {
+			t.Errorf("retryDelay(%q, %d) = %v, expected %v", tc.header, tc.attempt, got, tc.expected)
+		}
+	}
+}
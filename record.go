@@ -0,0 +1,325 @@
+// records with IONOS specific metadata
+package ionos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ErrRecordNotFound is returned (wrapped) when a record ID does not exist in
+// the zone.
+var ErrRecordNotFound = errors.New("record not found")
+
+// ConflictError is returned by UpdateRecordByID and ApplySync when the
+// record was changed since the expected change date.
+type ConflictError struct {
+	ID         string
+	Expected   time.Time
+	ChangeDate time.Time
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("record %s was changed at %s, expected %s",
+		e.ID, e.ChangeDate.Format(time.RFC3339Nano), e.Expected.Format(time.RFC3339Nano))
+}
+
+// Record is a libdns record together with the IONOS metadata of the record.
+// Record implements libdns.Record, so it can be passed to DeleteRecords and
+// SetRecords, which then target the record by its ID instead of its name
+// and type.
+type Record struct {
+	libdns.Record
+
+	// ID is the IONOS record ID.
+	ID string
+	// ChangeDate is the time of the last change of the record.
+	ChangeDate time.Time
+	// Disabled records are not served by the IONOS nameservers.
+	Disabled bool
+	// RootName is the name of the zone the record belongs to.
+	RootName string
+}
+
+func newRecord(r zoneRecord, zoneName string) (Record, error) {
+	result, err := fromIonosRecord(r, zoneName)
+	if err != nil {
+		return Record{}, err
+	}
+	changeDate, _ := time.Parse(time.RFC3339Nano, r.ChangeDate)
+	return Record{
+		Record:     result,
+		ID:         r.ID,
+		ChangeDate: changeDate,
+		Disabled:   r.Disabled,
+		RootName:   r.RootName,
+	}, nil
+}
+
+// asRecord returns r as Record, if it is one and has an ID.
+func asRecord(r libdns.Record) (Record, bool) {
+	switch rec := r.(type) {
+	case Record:
+		return rec, rec.ID != ""
+	case *Record:
+		if rec != nil {
+			return *rec, rec.ID != ""
+		}
+	}
+	return Record{}, false
+}
+
+// modifyRR returns a copy of r modified by fn, of the same type as r if
+// possible.
+func modifyRR(r libdns.Record, fn func(rr *libdns.RR)) libdns.Record {
+	switch rec := r.(type) {
+	case Record:
+		rec.Record = modifyRR(rec.Record, fn)
+		return rec
+	case *Record:
+		c := *rec
+		c.Record = modifyRR(rec.Record, fn)
+		return &c
+	}
+	rr := r.RR()
+	fn(&rr)
+	if _, ok := r.(libdns.RR); ok {
+		return rr
+	}
+	if parsed, err := rr.Parse(); err == nil {
+		return parsed
+	}
+	return rr
+}
+
+// isNotFound reports whether err is a 404 answer of the IONOS API.
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// GetRecordsDetailed lists all the records in the zone, including their IONOS
+// metadata.
+func (p *Provider) GetRecordsDetailed(ctx context.Context, zoneName string) ([]Record, error) {
+	zoneDes, err := p.findZoneByName(ctx, zoneName)
+	if err != nil {
+		return nil, fmt.Errorf("find zone: %w", err)
+	}
+
+	zoneResp, err := ionosGetZone(ctx, p.client(), zoneDes.ID, "", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("get zone records: %w", err)
+	}
+
+	records := make([]Record, len(zoneResp.Records))
+	for i, r := range zoneResp.Records {
+		record, err := newRecord(r, zoneDes.Name)
+		if err != nil {
+			return records, fmt.Errorf("convert record: %w", err)
+		}
+		records[i] = p.outputDetailed(record)
+	}
+	return records, nil
+}
+
+// RecordFilter selects records by GetRecordsFiltered. Empty fields match
+// all records.
+type RecordFilter struct {
+	// Type is the record type, e.g. "TXT".
+	Type string
+	// Name is the name of the records, relative to the zone.
+	Name string
+	// Suffix selects the records with the given name, relative to the zone,
+	// and all records below it, e.g. "dev" selects "dev" and "www.dev", but
+	// not "mydev".
+	Suffix string
+}
+
+// GetRecordsFiltered lists the records in the zone which match filter. The
+// filter is applied by IONOS, so only the matching records are transferred.
+func (p *Provider) GetRecordsFiltered(ctx context.Context, zone string, filter RecordFilter) ([]Record, error) {
+	zoneDes, err := p.findZoneByName(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("find zone: %w", err)
+	}
+
+	var name, suffix string
+	if filter.Name != "" {
+		name = canonicalName(libdns.AbsoluteName(filter.Name, zoneDes.Name))
+	}
+	if filter.Suffix != "" {
+		suffix = canonicalName(libdns.AbsoluteName(filter.Suffix, zoneDes.Name))
+	}
+	zoneResp, err := ionosGetZone(ctx, p.client(), zoneDes.ID, strings.ToUpper(filter.Type), name, suffix)
+	if err != nil {
+		return nil, fmt.Errorf("get zone records: %w", err)
+	}
+
+	var records []Record
+	for _, r := range zoneResp.Records {
+		// IONOS matches the suffix as plain string, without regard for
+		// label boundaries
+		if n := canonicalName(r.Name); suffix != "" && n != suffix && !strings.HasSuffix(n, "."+suffix) {
+			continue
+		}
+		record, err := newRecord(r, zoneDes.Name)
+		if err != nil {
+			return records, fmt.Errorf("convert record: %w", err)
+		}
+		records = append(records, p.outputDetailed(record))
+	}
+	return records, nil
+}
+
+// getRecordByID reads the record with the given ID. A missing record is
+// reported as ErrRecordNotFound.
+func (p *Provider) getRecordByID(ctx context.Context, zoneDes zoneDescriptor, id string) (zoneRecord, error) {
+	existing, err := ionosGetRecord(ctx, p.client(), zoneDes.ID, id)
+	if isNotFound(err) {
+		return zoneRecord{}, fmt.Errorf("%w (%s): %w", ErrRecordNotFound, id, err)
+	}
+	if err != nil {
+		return zoneRecord{}, fmt.Errorf("get record %s: %w", id, err)
+	}
+	return existing, nil
+}
+
+// GetRecordByID returns the record with the given IONOS ID.
+func (p *Provider) GetRecordByID(ctx context.Context, zone, id string) (Record, error) {
+	zoneDes, err := p.findZoneByName(ctx, zone)
+	if err != nil {
+		return Record{}, fmt.Errorf("find zone: %w", err)
+	}
+	existing, err := p.getRecordByID(ctx, zoneDes, id)
+	if err != nil {
+		return Record{}, err
+	}
+	rec, err := newRecord(existing, zoneDes.Name)
+	return p.outputDetailed(rec), err
+}
+
+// UpdateRecordByID replaces the content, TTL and priority of the record with
+// the given ID by those of r, with the TTL normalized by p.TTLPolicy. If r is
+// a [Record], its disabled flag is set as well, otherwise the flag is kept.
+// IONOS does not allow to change the name or type of a record.
+//
+// If expected is not zero, the record is only updated if its change date
+// still equals expected; otherwise a *ConflictError is returned. The IONOS
+// API has no conditional requests, so the check narrows, but does not
+// close, the window for concurrent changes.
+//
+// UpdateRecordByID returns the record as stored by IONOS after the update.
+func (p *Provider) UpdateRecordByID(ctx context.Context, zone, id string, r libdns.Record, expected time.Time) (Record, error) {
+	r, err := p.applyTTL(r)
+	if err != nil {
+		return Record{}, err
+	}
+	zoneDes, err := p.findZoneByName(ctx, zone)
+	if err != nil {
+		return Record{}, fmt.Errorf("find zone: %w", err)
+	}
+	ctx, unlock, err := p.lockZone(ctx, zoneDes)
+	if err != nil {
+		return Record{}, err
+	}
+	defer unlock()
+	existing, err := p.getRecordByID(ctx, zoneDes, id)
+	if err != nil {
+		return Record{}, err
+	}
+	current, err := newRecord(existing, zoneDes.Name)
+	if err != nil {
+		return Record{}, fmt.Errorf("convert record: %w", err)
+	}
+	if !expected.IsZero() && !current.ChangeDate.Equal(expected) {
+		return current, &ConflictError{ID: id, Expected: expected, ChangeDate: current.ChangeDate}
+	}
+
+	if err := checkSameNameAndType(existing, r, zoneDes.Name); err != nil {
+		return current, err
+	}
+	if err := p.Guard.checkChange(zoneDes.Name, existing.Name, existing.Type); err != nil {
+		return current, err
+	}
+	req := toIonosRecord(r, zoneDes.Name)
+	if _, ok := asRecord(r); !ok {
+		req.Disabled = existing.Disabled
+	}
+	if err := ionosUpdateRecord(ctx, p.client(), zoneDes.ID, id, req); err != nil {
+		return current, fmt.Errorf("update record %s: %w", id, err)
+	}
+	if IsDryRun(ctx) {
+		return Record{Record: r, ID: id, ChangeDate: current.ChangeDate, Disabled: req.Disabled, RootName: current.RootName}, nil
+	}
+	updated, err := p.getRecordByID(ctx, zoneDes, id)
+	if err != nil {
+		return current, err
+	}
+	rec, err := newRecord(updated, zoneDes.Name)
+	return p.outputDetailed(rec), err
+}
+
+// checkSameNameAndType returns an error if r, relative to zone, does not
+// have the name and type of the existing record, since IONOS does not allow
+// to change them.
+func checkSameNameAndType(existing zoneRecord, r libdns.Record, zone string) error {
+	rr := r.RR()
+	name := libdns.AbsoluteName(rr.Name, zone)
+	if !sameName(name, existing.Name) || !strings.EqualFold(rr.Type, existing.Type) {
+		return fmt.Errorf("record %s is %s %s, can not change it to %s %s",
+			existing.ID, existing.Name, existing.Type, name, rr.Type)
+	}
+	return nil
+}
+
+// DeleteRecordByID deletes the record with the given ID and returns it.
+func (p *Provider) DeleteRecordByID(ctx context.Context, zone, id string) (Record, error) {
+	zoneDes, err := p.findZoneByName(ctx, zone)
+	if err != nil {
+		return Record{}, fmt.Errorf("find zone: %w", err)
+	}
+	ctx, unlock, err := p.lockZone(ctx, zoneDes)
+	if err != nil {
+		return Record{}, err
+	}
+	defer unlock()
+	deleted, found, err := p.deleteRecordByID(ctx, zoneDes, id)
+	if err != nil {
+		return Record{}, err
+	}
+	if !found {
+		return Record{}, fmt.Errorf("%w (%s)", ErrRecordNotFound, id)
+	}
+	return p.outputDetailed(deleted), nil
+}
+
+// deleteRecordByID deletes the record with the given ID and returns it. If
+// the record does not exist, ok is false.
+func (p *Provider) deleteRecordByID(ctx context.Context, zoneDes zoneDescriptor, id string) (rec Record, ok bool, err error) {
+	existing, err := p.getRecordByID(ctx, zoneDes, id)
+	if errors.Is(err, ErrRecordNotFound) {
+		return Record{}, false, nil
+	}
+	if err != nil {
+		return Record{}, false, err
+	}
+	if err := checkApexDelete(existing.Name, existing.Type, zoneDes.Name); err != nil {
+		return Record{}, false, err
+	}
+	if err := p.Guard.checkChange(zoneDes.Name, existing.Name, existing.Type); err != nil {
+		return Record{}, false, err
+	}
+	rec, err = newRecord(existing, zoneDes.Name)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("convert record: %w", err)
+	}
+	if err := ionosDeleteRecord(ctx, p.client(), zoneDes.ID, id); err != nil {
+		return Record{}, false, fmt.Errorf("delete record %s: %w", id, err)
+	}
+	return rec, true, nil
+}
@@ -0,0 +1,224 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_GetRecordsDetailed(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	www := fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	records, err := p.GetRecordsDetailed(context.TODO(), "example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %+v", records)
+	}
+	r := records[0]
+	if r.ID != www.ID || r.RootName != "example.com" || r.ChangeDate.IsZero() || r.Disabled {
+		t.Fatalf("unexpected metadata %+v", r)
+	}
+	if rr := r.RR(); rr.Name != "www" || rr.Type != "A" || rr.Data != "1.2.3.4" {
+		t.Fatalf("unexpected record %+v", rr)
+	}
+}
+
+func Test_MXPreference(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	if _, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.MX{Name: "@", Preference: 10, Target: "mx.example.net", TTL: time.Hour},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	records := fs.records(zone)
+	if len(records) != 1 || records[0].Content != "mx.example.net" || records[0].Prio != 10 {
+		t.Fatalf("expected the preference in the prio field, got %+v", records)
+	}
+
+	got, err := p.GetRecords(context.TODO(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mx, ok := got[0].(libdns.MX); !ok || mx.Preference != 10 || mx.Target != "mx.example.net" {
+		t.Fatalf("unexpected record %+v", got[0])
+	}
+}
+
+func Test_RecordsByID(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "txt", "TXT", "one", 300)
+	fs.addRecord(zone, "txt", "TXT", "two", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	records, err := p.GetRecordsDetailed(context.TODO(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var one, two Record
+	for _, r := range records {
+		switch r.RR().Data {
+		case "one":
+			one = r
+		case "two":
+			two = r
+		}
+	}
+
+	// update exactly one of two records with the same name and type
+	two.Record = libdns.TXT{Name: "txt", Text: "three", TTL: two.RR().TTL}
+	two.Disabled = true
+	if _, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{two}); err != nil {
+		t.Fatal(err)
+	}
+	after := fs.records(zone)
+	if len(after) != 2 || after[1].ID != two.ID || after[1].Content != `"three"` || !after[1].Disabled {
+		t.Fatalf("expected record %s to be updated, got %+v", two.ID, after)
+	}
+
+	// the name and type of a record can not be changed
+	renamed := two
+	renamed.Record = libdns.TXT{Name: "other", Text: "three", TTL: two.RR().TTL}
+	if _, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{renamed}); err == nil {
+		t.Fatal("expected error when changing the name of a record")
+	}
+	retyped := two
+	retyped.Record = libdns.RR{Name: "txt", Type: "A", Data: "1.2.3.4", TTL: two.RR().TTL}
+	if _, err := p.SetRecords(context.TODO(), "example.com", []libdns.Record{retyped}); err == nil {
+		t.Fatal("expected error when changing the type of a record")
+	}
+	if n := fs.requestCount("PUT"); n != 1 {
+		t.Fatalf("expected 1 PUT request, got %d", n)
+	}
+
+	// delete exactly one of them; deleting it again is a no-op
+	for i := 0; i < 2; i++ {
+		deleted, err := p.DeleteRecords(context.TODO(), "example.com", []libdns.Record{&one})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && (len(deleted) != 1 || deleted[0].(Record).ID != one.ID) {
+			t.Fatalf("expected record %s to be deleted, got %+v", one.ID, deleted)
+		}
+		if i == 1 && len(deleted) != 0 {
+			t.Fatalf("expected no record to be deleted, got %+v", deleted)
+		}
+	}
+	if after := fs.records(zone); len(after) != 1 || after[0].ID != two.ID {
+		t.Fatalf("expected only record %s to be left, got %+v", two.ID, after)
+	}
+}
+
+func Test_RecordByIDAPI(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	www := fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+
+	locker := &recordingLocker{}
+	p := &Provider{AuthAPIToken: "public.secret", Locker: locker}
+	got, err := p.GetRecordByID(context.TODO(), "example.com", www.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != www.ID || got.RR().Data != "1.2.3.4" {
+		t.Fatalf("unexpected record %+v", got)
+	}
+	if _, err := p.GetRecordByID(context.TODO(), "example.com", "missing"); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// the first update succeeds and changes the change date, so that a
+	// second update based on the same state is refused
+	change := libdns.RR{Name: "www", Type: "A", Data: "1.2.3.5", TTL: time.Minute}
+	updated, err := p.UpdateRecordByID(context.TODO(), "example.com", www.ID, change, got.ChangeDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.RR().Data != "1.2.3.5" || updated.RR().TTL != time.Minute || updated.ChangeDate.Equal(got.ChangeDate) {
+		t.Fatalf("unexpected updated record %+v", updated)
+	}
+	var conflict *ConflictError
+	change.Data = "1.2.3.6"
+	if _, err := p.UpdateRecordByID(context.TODO(), "example.com", www.ID, change, got.ChangeDate); !errors.As(err, &conflict) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	if conflict.ID != www.ID || !conflict.ChangeDate.Equal(updated.ChangeDate) {
+		t.Fatalf("unexpected conflict %+v", conflict)
+	}
+	if _, err := p.UpdateRecordByID(context.TODO(), "example.com", www.ID, libdns.RR{Name: "ftp", Type: "A", Data: "1.2.3.6"}, time.Time{}); err == nil {
+		t.Fatal("expected error when changing the name of a record")
+	}
+
+	deleted, err := p.DeleteRecordByID(context.TODO(), "example.com", www.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.ID != www.ID || deleted.RR().Data != "1.2.3.5" {
+		t.Fatalf("unexpected deleted record %+v", deleted)
+	}
+	if _, err := p.DeleteRecordByID(context.TODO(), "example.com", www.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// updates and deletes lock the zone, reads do not
+	if len(locker.locked) != 5 || locker.held != 0 {
+		t.Fatalf("expected the zone to be locked 5 times, got %v, %d held", locker.locked, locker.held)
+	}
+}
+
+func Test_GetRecordsFiltered(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "dev", "A", "1.2.3.1", 300)
+	fs.addRecord(zone, "www.dev", "A", "1.2.3.2", 300)
+	fs.addRecord(zone, "www.dev", "TXT", "hello", 300)
+	fs.addRecord(zone, "mydev", "A", "1.2.3.3", 300)
+	fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	tests := []struct {
+		filter RecordFilter
+		want   []string
+	}{
+		{RecordFilter{}, []string{"dev A", "www.dev A", "www.dev TXT", "mydev A", "www A"}},
+		{RecordFilter{Type: "txt"}, []string{"www.dev TXT"}},
+		{RecordFilter{Name: "WWW.dev"}, []string{"www.dev A", "www.dev TXT"}},
+		{RecordFilter{Name: "www.dev", Type: "A"}, []string{"www.dev A"}},
+		{RecordFilter{Suffix: "dev"}, []string{"dev A", "www.dev A", "www.dev TXT"}},
+		{RecordFilter{Suffix: "dev", Type: "TXT"}, []string{"www.dev TXT"}},
+		{RecordFilter{Name: "missing"}, nil},
+	}
+	for _, tt := range tests {
+		records, err := p.GetRecordsFiltered(context.TODO(), "example.com", tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]bool)
+		for _, r := range records {
+			got[r.RR().Name+" "+r.RR().Type] = true
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%+v: expected %v, got %v", tt.filter, tt.want, got)
+			continue
+		}
+		for _, w := range tt.want {
+			if !got[w] {
+				t.Errorf("%+v: expected %v, got %v", tt.filter, tt.want, got)
+			}
+		}
+	}
+	if n := fs.requestCount("GET"); n != 2*len(tests) {
+		t.Errorf("expected one zone request per filter, got %d GET requests", n)
+	}
+}
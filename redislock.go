@@ -0,0 +1,201 @@
+// Redis based distributed lock
+package ionos
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisLocker is a Locker using a Redis compatible server. A lock is a key
+// set with SET NX PX to a random token of its holder, and released with a
+// script which deletes the key only if it still holds the token. Locks
+// expire after TTL, so that a crashed process does not block the zone
+// forever; the holder extends the expiry every third of TTL while holding
+// the lock.
+//
+// A new connection is used for every command, so RedisLocker needs no
+// cleanup.
+type RedisLocker struct {
+	// Addr is the host:port of the server.
+	Addr string
+	// Password is sent with AUTH, if not empty.
+	Password string
+	// TTL defaults to 2 minutes.
+	TTL time.Duration
+	// PollInterval is the delay between attempts to acquire a held lock,
+	// 100ms by default.
+	PollInterval time.Duration
+}
+
+// redisUnlockScript deletes KEYS[1] if its value is ARGV[1].
+const redisUnlockScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+
+// redisRenewScript resets the expiry of KEYS[1] to ARGV[2] milliseconds if
+// its value is ARGV[1].
+const redisRenewScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+
+// errRedisNil is returned by readRedisReply for a nil reply.
+var errRedisNil = errors.New("redis: nil reply")
+
+// Lock sets the key, waiting while another holder has it.
+func (l *RedisLocker) Lock(ctx context.Context, key string) (context.Context, func() error, error) {
+	token, err := newLockToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	ttl := l.TTL
+	if ttl <= 0 {
+		ttl = defaultLockTTL
+	}
+	px := strconv.FormatInt(ttl.Milliseconds(), 10)
+
+	for {
+		_, err := l.do(ctx, "SET", key, token, "NX", "PX", px)
+		if err == nil {
+			held, release := holdLock(ctx, ttl, func(ctx context.Context) (bool, error) {
+				reply, err := l.do(ctx, "EVAL", redisRenewScript, "1", key, token, px)
+				return reply == int64(1), err
+			})
+			return held, func() error {
+				release()
+				// the context of the lock may be done already
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				_, err := l.do(ctx, "EVAL", redisUnlockScript, "1", key, token)
+				return err
+			}, nil
+		}
+		if !errors.Is(err, errRedisNil) {
+			return nil, nil, fmt.Errorf("acquire lock: %w", err)
+		}
+		if err := waitForLock(ctx, l.PollInterval); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// do runs a single command on a new connection and returns its reply.
+func (l *RedisLocker) do(ctx context.Context, args ...string) (any, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", l.Addr)
+	if err != nil {
+		if ctxErr := contextError(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	defer conn.Close()
+	// abort blocking reads and writes when ctx is done
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	if l.Password != "" {
+		if _, err := redisCommand(rw, "AUTH", l.Password); err != nil {
+			if ctxErr := contextError(ctx); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+	}
+	reply, err := redisCommand(rw, args...)
+	if err != nil {
+		// report the cancellation instead of the i/o timeout it caused
+		if ctxErr := contextError(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+	}
+	return reply, err
+}
+
+// contextError returns the error of ctx. Unlike ctx.Err, it also reports a
+// passed deadline before the context is marked as done.
+func contextError(ctx context.Context) error {
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return ctx.Err()
+}
+
+// redisCommand writes a command in RESP format and reads the reply.
+func redisCommand(rw *bufio.ReadWriter, args ...string) (any, error) {
+	fmt.Fprintf(rw, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(rw, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := rw.Flush(); err != nil {
+		return nil, err
+	}
+	return readRedisReply(rw.Reader)
+}
+
+// readRedisReply reads a RESP reply. Simple strings and bulk strings are
+// returned as string, integers as int64 and arrays as []any. Error
+// replies are returned as error, nil replies as errRedisNil.
+func readRedisReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid array length %q", line)
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		elems := make([]any, n)
+		for i := range elems {
+			elems[i], err = readRedisReply(r)
+			if err != nil && !errors.Is(err, errRedisNil) {
+				return nil, err
+			}
+		}
+		return elems, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+// Interface guard
+var _ Locker = (*RedisLocker)(nil)
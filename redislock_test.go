@@ -0,0 +1,191 @@
+package ionos
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-memory stand-in for a Redis server, supporting the
+// commands used by RedisLocker.
+type fakeRedis struct {
+	password string
+
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+// newFakeRedis starts a fake Redis server and returns its address.
+func newFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	r := &fakeRedis{password: password, values: map[string]string{}, expires: map[string]time.Time{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r, ln.Addr().String()
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authenticated := r.password == ""
+	for {
+		reply, err := readRedisReply(rd)
+		if err != nil {
+			return
+		}
+		elems, _ := reply.([]any)
+		args := make([]string, len(elems))
+		for i, e := range elems {
+			args[i], _ = e.(string)
+		}
+		if len(args) == 0 {
+			fmt.Fprint(conn, "-ERR empty command\r\n")
+			continue
+		}
+		if strings.ToUpper(args[0]) == "AUTH" {
+			if len(args) == 2 && args[1] == r.password {
+				authenticated = true
+				fmt.Fprint(conn, "+OK\r\n")
+			} else {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+			}
+			continue
+		}
+		if !authenticated {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		fmt.Fprint(conn, r.exec(args))
+	}
+}
+
+// get returns the value of key, removing it if expired. r.mu must be held.
+func (r *fakeRedis) get(key string) (string, bool) {
+	if exp, ok := r.expires[key]; ok && time.Now().After(exp) {
+		delete(r.values, key)
+		delete(r.expires, key)
+	}
+	v, ok := r.values[key]
+	return v, ok
+}
+
+func (r *fakeRedis) exec(args []string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "SET":
+		// SET key value NX PX ms
+		if len(args) != 6 || strings.ToUpper(args[3]) != "NX" || strings.ToUpper(args[4]) != "PX" {
+			return "-ERR unsupported SET\r\n"
+		}
+		ms, err := strconv.Atoi(args[5])
+		if err != nil {
+			return "-ERR invalid expire time\r\n"
+		}
+		if _, ok := r.get(args[1]); ok {
+			return "$-1\r\n"
+		}
+		r.values[args[1]] = args[2]
+		r.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return "+OK\r\n"
+	case "EVAL":
+		// only the scripts of RedisLocker are supported
+		switch {
+		case len(args) == 5 && args[1] == redisUnlockScript && args[2] == "1":
+			if v, ok := r.get(args[3]); ok && v == args[4] {
+				delete(r.values, args[3])
+				delete(r.expires, args[3])
+				return ":1\r\n"
+			}
+			return ":0\r\n"
+		case len(args) == 6 && args[1] == redisRenewScript && args[2] == "1":
+			ms, err := strconv.Atoi(args[5])
+			if err != nil {
+				return "-ERR invalid expire time\r\n"
+			}
+			if v, ok := r.get(args[3]); ok && v == args[4] {
+				r.expires[args[3]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+				return ":1\r\n"
+			}
+			return ":0\r\n"
+		default:
+			return "-ERR unsupported script\r\n"
+		}
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func Test_RedisLocker(t *testing.T) {
+	_, addr := newFakeRedis(t, "secret")
+	testLocker(t, &RedisLocker{Addr: addr, Password: "secret", PollInterval: time.Millisecond})
+}
+
+func Test_RedisLockerExpiryAndOwnership(t *testing.T) {
+	r, addr := newFakeRedis(t, "")
+	l := &RedisLocker{Addr: addr, TTL: 20 * time.Millisecond, PollInterval: time.Millisecond}
+
+	// the lock of a crashed holder expires
+	r.mu.Lock()
+	r.values["key"] = "crashed"
+	r.expires["key"] = time.Now().Add(l.TTL)
+	r.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	_, unlock, err := l.Lock(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a holder whose lock expired does not release the lock of another
+	r.mu.Lock()
+	r.values["key"] = "other"
+	r.expires["key"] = time.Now().Add(time.Minute)
+	r.mu.Unlock()
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	r.mu.Lock()
+	v := r.values["key"]
+	r.mu.Unlock()
+	if v != "other" {
+		t.Fatalf("expected lock of other holder to be kept, got %q", v)
+	}
+}
+
+func Test_RedisLockerRenewal(t *testing.T) {
+	r, addr := newFakeRedis(t, "")
+	l := &RedisLocker{Addr: addr, TTL: 30 * time.Millisecond, PollInterval: time.Millisecond}
+	testLockRenewal(t, l, l.TTL, func() {
+		r.mu.Lock()
+		r.values["key"] = "other"
+		r.mu.Unlock()
+	})
+}
+
+func Test_RedisLockerErrors(t *testing.T) {
+	_, addr := newFakeRedis(t, "secret")
+	l := &RedisLocker{Addr: addr, Password: "wrong"}
+	if _, _, err := l.Lock(context.TODO(), "key"); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatalf("expected authentication error, got %v", err)
+	}
+}
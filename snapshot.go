@@ -0,0 +1,377 @@
+// zone snapshots and point-in-time restore
+package ionos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// SnapshotVersion is the version of the snapshot format written by
+// Snapshot. Restore and FileSnapshotStore refuse newer versions.
+const SnapshotVersion = 1
+
+// Snapshot holds the raw records of a zone at a point in time, including
+// the IONOS specific data which is not part of libdns records.
+type Snapshot struct {
+	Version int              `json:"version"`
+	Zone    string           `json:"zone"`
+	ZoneID  string           `json:"zoneId"`
+	TakenAt time.Time        `json:"takenAt"`
+	Records []SnapshotRecord `json:"records"`
+}
+
+// SnapshotRecord is a record as returned by the IONOS API.
+type SnapshotRecord struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	RootName   string `json:"rootName"`
+	Type       string `json:"type"`
+	Content    string `json:"content"`
+	ChangeDate string `json:"changeDate"`
+	TTL        int    `json:"ttl"`
+	Prio       int    `json:"prio"`
+	Disabled   bool   `json:"disabled"`
+}
+
+// Snapshot captures all records of zone.
+func (p *Provider) Snapshot(ctx context.Context, zone string) (*Snapshot, error) {
+	zoneDes, err := p.findZoneByName(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("find zone: %w", err)
+	}
+	resp, err := ionosGetZone(ctx, p.client(), zoneDes.ID, "", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("get zone records: %w", err)
+	}
+
+	snap := &Snapshot{
+		Version: SnapshotVersion,
+		Zone:    zoneDes.Name,
+		ZoneID:  zoneDes.ID,
+		TakenAt: time.Now().UTC(),
+		Records: make([]SnapshotRecord, len(resp.Records)),
+	}
+	for i, r := range resp.Records {
+		snap.Records[i] = SnapshotRecord(r)
+	}
+	return snap, nil
+}
+
+// restoreUnchanged reports whether the existing record e already has the
+// state of the snapshot record w.
+func restoreUnchanged(e, w zoneRecord) bool {
+	return e.Content == w.Content && e.TTL == w.TTL && e.Prio == w.Prio && e.Disabled == w.Disabled
+}
+
+// restoreKey identifies records which can be restored by an update.
+type restoreKey struct {
+	name, typ, content string
+}
+
+func newRestoreKey(name, typ, content string) restoreKey {
+	return restoreKey{canonicalName(name), strings.ToUpper(typ), content}
+}
+
+// Restore brings zone back to the state of snap, using as few API calls as
+// possible. Records still present with the same ID, or with the same name,
+// type and content, are kept, and updated if their TTL, priority or
+// disabled flag differ. Missing records are recreated in a single request,
+// and records not in the snapshot are deleted. The SOA and apex NS records
+// are managed by IONOS and not touched.
+//
+// The TTLs of the snapshot are normalized by p.TTLPolicy. Recreated records
+// get new IONOS IDs. Restore returns the changes made, in the order of
+// updates, creates and deletes.
+func (p *Provider) Restore(ctx context.Context, zone string, snap *Snapshot) ([]SyncChange, error) {
+	if snap.Version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	zoneDes, err := p.findZoneByName(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("find zone: %w", err)
+	}
+	ctx, unlock, err := p.lockZone(ctx, zoneDes)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	resp, err := ionosGetZone(ctx, p.client(), zoneDes.ID, "", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("get zone records: %w", err)
+	}
+
+	managed := func(r zoneRecord) bool {
+		name := relativeName(r.Name, zoneDes.Name)
+		return isManagedRecord(libdns.RR{Name: name, Type: r.Type})
+	}
+
+	current := make(map[string]zoneRecord)
+	for _, r := range resp.Records {
+		if !managed(r) {
+			current[r.ID] = r
+		}
+	}
+
+	// pair the records of the snapshot with current records, first by ID,
+	// then by name, type and content
+	type pair struct {
+		want     zoneRecord
+		existing *zoneRecord
+	}
+	pairs := make([]pair, 0, len(snap.Records))
+	for _, sr := range snap.Records {
+		want := zoneRecord(sr)
+		want.Name = libdns.AbsoluteName(relativeName(want.Name, snap.Zone), zoneDes.Name)
+		if managed(want) {
+			continue
+		}
+		if want.TTL, err = p.applyTTLSeconds(want.TTL); err != nil {
+			return nil, err
+		}
+		if existing, ok := current[want.ID]; ok && sameName(existing.Name, want.Name) && existing.Type == want.Type {
+			delete(current, want.ID)
+			pairs = append(pairs, pair{want: want, existing: &existing})
+			continue
+		}
+		pairs = append(pairs, pair{want: want})
+	}
+	byContent := make(map[restoreKey][]zoneRecord)
+	for _, r := range current {
+		key := newRestoreKey(r.Name, r.Type, r.Content)
+		byContent[key] = append(byContent[key], r)
+	}
+	for i := range pairs {
+		if pairs[i].existing != nil {
+			continue
+		}
+		key := newRestoreKey(pairs[i].want.Name, pairs[i].want.Type, pairs[i].want.Content)
+		if candidates := byContent[key]; len(candidates) > 0 {
+			existing := candidates[0]
+			byContent[key] = candidates[1:]
+			delete(current, existing.ID)
+			pairs[i].existing = &existing
+		}
+	}
+
+	var changes []SyncChange
+	convert := func(r zoneRecord) libdns.Record {
+		result, err := fromIonosRecord(r, zoneDes.Name)
+		if err != nil {
+			return libdns.RR{Name: relativeName(r.Name, zoneDes.Name), Type: r.Type, Data: r.Content}
+		}
+		return result
+	}
+
+	// records not in the snapshot, in a stable order
+	obsolete := make([]zoneRecord, 0, len(current))
+	for _, r := range current {
+		obsolete = append(obsolete, r)
+	}
+	sort.Slice(obsolete, func(i, j int) bool { return obsolete[i].ID < obsolete[j].ID })
+
+	// check all updates and deletes before making any change
+	for _, pr := range pairs {
+		if e := pr.existing; e != nil && !restoreUnchanged(*e, pr.want) {
+			if err := p.Guard.checkChange(zoneDes.Name, e.Name, e.Type); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, r := range obsolete {
+		if err := p.Guard.checkChange(zoneDes.Name, r.Name, r.Type); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.Guard.checkDeletions(zoneDes.Name, len(obsolete)); err != nil {
+		return nil, err
+	}
+
+	// updates
+	var creates []zoneRecord
+	for _, pr := range pairs {
+		if pr.existing == nil {
+			creates = append(creates, pr.want)
+			continue
+		}
+		e, w := *pr.existing, pr.want
+		if restoreUnchanged(e, w) {
+			continue
+		}
+		if err := ionosUpdateRecord(ctx, p.client(), zoneDes.ID, e.ID, restoreRecord(w)); err != nil {
+			return changes, fmt.Errorf("update record %s: %w", e.ID, err)
+		}
+		changes = append(changes, SyncChange{Action: SyncUpdate, RecordID: e.ID, Old: convert(e), New: convert(w)})
+	}
+
+	// creates, in a single request
+	if len(creates) > 0 {
+		reqs := make([]record, len(creates))
+		for i, r := range creates {
+			reqs[i] = restoreRecord(r)
+		}
+		created, err := ionosCreateRecords(ctx, p.client(), zoneDes.ID, reqs)
+		if err != nil {
+			return changes, fmt.Errorf("create records: %w", err)
+		}
+		for i, r := range creates {
+			c := SyncChange{Action: SyncCreate, New: convert(r)}
+			if i < len(created) {
+				c.RecordID = created[i].ID
+			}
+			changes = append(changes, c)
+		}
+	}
+
+	// deletes
+	for _, r := range obsolete {
+		if err := ionosDeleteRecord(ctx, p.client(), zoneDes.ID, r.ID); err != nil {
+			return changes, fmt.Errorf("delete record %s: %w", r.ID, err)
+		}
+		changes = append(changes, SyncChange{Action: SyncDelete, RecordID: r.ID, Old: convert(r)})
+	}
+	return changes, nil
+}
+
+// restoreRecord returns the request to create or update a record from a
+// snapshot.
+func restoreRecord(r zoneRecord) record {
+	ttl := r.TTL
+	content := r.Content
+	// IONOS returns TXT records quoted, but expects them unquoted
+	if r.Type == "TXT" {
+		if text, err := strconv.Unquote(content); err == nil {
+			content = text
+		}
+	}
+	return record{
+		Name:     r.Name,
+		Type:     r.Type,
+		Content:  content,
+		TTL:      &ttl,
+		Prio:     r.Prio,
+		Disabled: r.Disabled,
+	}
+}
+
+// SnapshotStore stores snapshots. Snapshots are identified by the zone name
+// and an ID assigned by the store.
+type SnapshotStore interface {
+	// Save stores snap and returns its ID.
+	Save(ctx context.Context, snap *Snapshot) (string, error)
+	// Load returns the snapshot of zone with the given ID.
+	Load(ctx context.Context, zone, id string) (*Snapshot, error)
+	// List returns the IDs of all snapshots of zone, oldest first.
+	List(ctx context.Context, zone string) ([]string, error)
+}
+
+// FileSnapshotStore stores snapshots as JSON files in Dir, one directory
+// per zone. The ID of a snapshot is derived from the time it was taken.
+type FileSnapshotStore struct {
+	Dir string
+}
+
+const snapshotIDFormat = "20060102T150405.000000000Z"
+
+func (s *FileSnapshotStore) zoneDir(zone string) (string, error) {
+	name := canonicalName(zone)
+	if name == "" || strings.ContainsAny(name, `/\`) || name == ".." {
+		return "", fmt.Errorf("invalid zone name %q", zone)
+	}
+	return filepath.Join(s.Dir, name), nil
+}
+
+// Save writes snap to <Dir>/<zone>/<id>.json.
+func (s *FileSnapshotStore) Save(ctx context.Context, snap *Snapshot) (string, error) {
+	dir, err := s.zoneDir(snap.Zone)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("create snapshot directory: %w", err)
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal snapshot: %w", err)
+	}
+
+	id := snap.TakenAt.UTC().Format(snapshotIDFormat)
+	path := filepath.Join(dir, id+".json")
+	// write to a temporary file first, so that a crash does not leave a
+	// truncated snapshot behind
+	tmp, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return "", fmt.Errorf("write snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("write snapshot: %w", err)
+	}
+	return id, nil
+}
+
+// Load reads the snapshot with the given ID.
+func (s *FileSnapshotStore) Load(ctx context.Context, zone, id string) (*Snapshot, error) {
+	dir, err := s.zoneDir(zone)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse(snapshotIDFormat, id); err != nil {
+		return nil, fmt.Errorf("invalid snapshot id %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parse snapshot: %w", err)
+	}
+	if snap.Version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	return &snap, nil
+}
+
+// List returns the IDs of the snapshots of zone, oldest first.
+func (s *FileSnapshotStore) List(ctx context.Context, zone string) ([]string, error) {
+	dir, err := s.zoneDir(zone)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		id := strings.TrimSuffix(e.Name(), ".json")
+		if _, err := time.Parse(snapshotIDFormat, id); err == nil && !e.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Interface guard
+var _ SnapshotStore = (*FileSnapshotStore)(nil)
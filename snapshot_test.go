@@ -0,0 +1,110 @@
+package ionos
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func Test_SnapshotAndRestore(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "@", "NS", "ns1.ui-dns.de", 86400)
+	fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+	changed := fs.addRecord(zone, "mail", "A", "1.2.3.5", 300)
+	deleted := fs.addRecord(zone, "txt", "TXT", "keep me", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	snap, err := p.Snapshot(context.TODO(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Version != SnapshotVersion || snap.ZoneID != zone.id || len(snap.Records) != 4 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+	before := fs.records(zone)
+
+	// simulate accidental changes
+	fs.mu.Lock()
+	delete(zone.records, deleted.ID)
+	r := zone.records[changed.ID]
+	r.Content = "9.9.9.9"
+	r.Disabled = true
+	zone.records[changed.ID] = r
+	fs.mu.Unlock()
+	fs.addRecord(zone, "new", "A", "1.2.3.6", 300)
+
+	changes, err := p.Restore(context.TODO(), "example.com", snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	for method, n := range map[string]int{"PUT": 1, "POST": 1, "DELETE": 1} {
+		if got := fs.requestCount(method); got != n {
+			t.Errorf("expected %d %s requests, got %d", n, method, got)
+		}
+	}
+
+	after := fs.records(zone)
+	if len(after) != len(before) {
+		t.Fatalf("expected %d records after restore, got %+v", len(before), after)
+	}
+	for i := range before {
+		b, a := before[i], after[i]
+		if a.Name != b.Name || a.Type != b.Type || a.Content != b.Content || a.TTL != b.TTL || a.Disabled != b.Disabled {
+			t.Errorf("record %d: expected %+v, got %+v", i, b, a)
+		}
+	}
+
+	// nothing left to do
+	changes, err = p.Restore(context.TODO(), "example.com", snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
+
+func Test_FileSnapshotStore(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	snap, err := p.Snapshot(context.TODO(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &FileSnapshotStore{Dir: t.TempDir()}
+	id, err := store.Save(context.TODO(), snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := store.List(context.TODO(), "example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != id {
+		t.Fatalf("expected snapshot ids [%s], got %v", id, ids)
+	}
+
+	loaded, err := store.Load(context.TODO(), "example.com", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.TakenAt.Equal(snap.TakenAt) {
+		t.Fatalf("expected snapshot taken at %v, got %v", snap.TakenAt, loaded.TakenAt)
+	}
+	loaded.TakenAt = snap.TakenAt
+	if !reflect.DeepEqual(loaded, snap) {
+		t.Fatalf("expected loaded snapshot to equal saved one:\n%+v\n%+v", snap, loaded)
+	}
+
+	if _, err := store.Load(context.TODO(), "example.com", "../../etc/passwd"); err == nil {
+		t.Fatal("expected error for invalid snapshot id")
+	}
+}
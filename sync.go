@@ -0,0 +1,408 @@
+// declarative zone sync with plan/apply
+package ionos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// SyncAction is the kind of a planned change.
+type SyncAction string
+
+const (
+	SyncCreate SyncAction = "create"
+	SyncUpdate SyncAction = "update"
+	SyncDelete SyncAction = "delete"
+)
+
+// SyncChange is a single change of a SyncPlan.
+type SyncChange struct {
+	Action SyncAction
+	// RecordID is the IONOS ID of the updated or deleted record. It is
+	// empty for creates.
+	RecordID string
+	// ChangeDate is the time of the last change of the updated or deleted
+	// record when the plan was made. ApplySync refuses to apply the plan if
+	// the record was changed since. It is zero for creates.
+	ChangeDate time.Time
+	// Old is the current record, nil for creates.
+	Old libdns.Record
+	// New is the desired record, nil for deletes.
+	New libdns.Record
+}
+
+// SyncOptions are the safety settings of a sync.
+type SyncOptions struct {
+	// MaxDeletions is the maximum number of records a plan may delete.
+	// PlanSync fails if more deletions would be needed. Zero means no
+	// limit.
+	MaxDeletions int
+
+	// ProtectedNames are names, relative to the zone, of records which
+	// are never created, updated or deleted by the sync. They are matched
+	// like the names of Guard.Protected, see ProtectedRecord, e.g. "_dmarc"
+	// or "*._domainkey". Unlike the Guard, which makes ApplySync fail,
+	// differing records with a protected name are left out of the plan and
+	// listed in SyncPlan.Protected.
+	ProtectedNames []string
+}
+
+// SyncPlan lists the changes needed to bring a zone to the desired state.
+// It is created by PlanSync and executed by ApplySync.
+type SyncPlan struct {
+	Zone    string
+	Changes []SyncChange
+	// Protected lists the records which were left untouched because their
+	// name is protected, although they differ from the desired state.
+	Protected []libdns.Record
+
+	zoneID string
+}
+
+// Empty reports whether the plan has no changes.
+func (plan *SyncPlan) Empty() bool {
+	return len(plan.Changes) == 0
+}
+
+// Count returns the number of changes with the given action.
+func (plan *SyncPlan) Count(action SyncAction) int {
+	n := 0
+	for _, c := range plan.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// String returns the plan in a human readable form, one change per line,
+// prefixed with "+" (create), "~" (update) or "-" (delete).
+func (plan *SyncPlan) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "zone %s: %d to create, %d to update, %d to delete\n", plan.Zone,
+		plan.Count(SyncCreate), plan.Count(SyncUpdate), plan.Count(SyncDelete))
+	for _, c := range plan.Changes {
+		switch c.Action {
+		case SyncCreate:
+			fmt.Fprintf(&sb, "+ %s\n", formatRR(c.New.RR()))
+		case SyncUpdate:
+			fmt.Fprintf(&sb, "~ %s\n", formatRR(c.Old.RR()))
+			fmt.Fprintf(&sb, "  => %s\n", formatRR(c.New.RR()))
+		case SyncDelete:
+			fmt.Fprintf(&sb, "- %s\n", formatRR(c.Old.RR()))
+		}
+	}
+	for _, r := range plan.Protected {
+		fmt.Fprintf(&sb, "! %s (protected, not changed)\n", formatRR(r.RR()))
+	}
+	return sb.String()
+}
+
+// syncKey identifies the RRset of a record.
+type syncKey struct {
+	name string // relative to zone, lowercase
+	typ  string
+}
+
+type syncRecord struct {
+	id         string // empty for desired records
+	changeDate time.Time
+	record     libdns.Record
+}
+
+// PlanSync computes the changes needed to make the records of zone exactly
+// match desired. Names of desired records are relative to zone. The SOA and
+// apex NS records are managed by IONOS and ignored. A desired TTL of 0
+// matches any TTL.
+//
+// Within an RRset (records of the same name and type), records with the
+// same data are kept. Remaining records are updated in place, so that
+// their IONOS IDs are kept. Surplus records are deleted or created.
+//
+// The TTLs of desired are normalized by p.TTLPolicy first, so that a plan
+// made again after ApplySync is empty. Nothing is changed until the plan is
+// passed to ApplySync.
+func (p *Provider) PlanSync(ctx context.Context, zone string, desired []libdns.Record, opts SyncOptions) (*SyncPlan, error) {
+	desired, err := p.applyTTLPolicy(desired)
+	if err != nil {
+		return nil, err
+	}
+	zoneDes, err := p.findZoneByName(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("find zone: %w", err)
+	}
+	resp, err := ionosGetZone(ctx, p.client(), zoneDes.ID, "", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("get zone records: %w", err)
+	}
+
+	current := make(map[syncKey][]syncRecord)
+	for _, zr := range resp.Records {
+		r, err := fromIonosRecord(zr, zoneDes.Name)
+		if err != nil {
+			return nil, fmt.Errorf("convert record: %w", err)
+		}
+		if isManagedRecord(r.RR()) {
+			continue
+		}
+		key := newSyncKey(r.RR(), zoneDes.Name)
+		changeDate, _ := time.Parse(time.RFC3339Nano, zr.ChangeDate)
+		current[key] = append(current[key], syncRecord{id: zr.ID, changeDate: changeDate, record: r})
+	}
+
+	wanted := make(map[syncKey][]syncRecord)
+	for _, r := range desired {
+		if isManagedRecord(r.RR()) {
+			continue
+		}
+		key := newSyncKey(r.RR(), zoneDes.Name)
+		wanted[key] = append(wanted[key], syncRecord{record: r})
+	}
+
+	keys := make([]syncKey, 0, len(current)+len(wanted))
+	for k := range current {
+		keys = append(keys, k)
+	}
+	for k := range wanted {
+		if _, ok := current[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ki, kj := canonicalKey(keys[i].name), canonicalKey(keys[j].name); ki != kj {
+			return ki < kj
+		}
+		return keys[i].typ < keys[j].typ
+	})
+
+	plan := &SyncPlan{Zone: zoneDes.Name, zoneID: zoneDes.ID}
+	for _, k := range keys {
+		changes := diffRRset(current[k], wanted[k])
+		if len(changes) == 0 {
+			continue
+		}
+		if isProtectedName(k.name, opts.ProtectedNames) {
+			for _, c := range current[k] {
+				plan.Protected = append(plan.Protected, c.record)
+			}
+			continue
+		}
+		plan.Changes = append(plan.Changes, changes...)
+	}
+
+	if n := plan.Count(SyncDelete); opts.MaxDeletions > 0 && n > opts.MaxDeletions {
+		return plan, fmt.Errorf("plan deletes %d records, more than the allowed maximum of %d", n, opts.MaxDeletions)
+	}
+	return plan, nil
+}
+
+func newSyncKey(rr libdns.RR, zone string) syncKey {
+	name := relativeName(libdns.AbsoluteName(rr.Name, zone), zone)
+	return syncKey{name: name, typ: strings.ToUpper(rr.Type)}
+}
+
+// isProtectedName reports whether name, relative to the zone, matches one
+// of the patterns, like the names of Guard.Protected.
+func isProtectedName(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if (ProtectedRecord{Name: pattern}).matches(name, "") {
+			return true
+		}
+	}
+	return false
+}
+
+// syncData returns the data of rr in a form suitable for comparison. Target
+// names are compared without trailing dot, since IONOS stores them without.
+// The data of other types is compared as is, e.g. TXT "foo." differs from
+// "foo".
+func syncData(rr libdns.RR) string {
+	switch strings.ToUpper(rr.Type) {
+	case "CNAME", "NS", "PTR", "DNAME", "MX", "SRV":
+		// MX and SRV data end with the target
+		return strings.TrimSuffix(rr.Data, ".")
+	}
+	return rr.Data
+}
+
+// diffRRset computes the changes to turn the records current of an RRset
+// into desired.
+func diffRRset(current, desired []syncRecord) []SyncChange {
+	cur := append([]syncRecord(nil), current...)
+	want := append([]syncRecord(nil), desired...)
+
+	// remove matching pairs, first with equal data and TTL, then with equal
+	// data only
+	matchers := []func(a, b libdns.RR) bool{
+		func(a, b libdns.RR) bool {
+			return syncData(a) == syncData(b) && (b.TTL == 0 || a.TTL == b.TTL)
+		},
+		func(a, b libdns.RR) bool { return syncData(a) == syncData(b) },
+	}
+	var changes []SyncChange
+	for pass, match := range matchers {
+		for i := 0; i < len(want); i++ {
+			for j := 0; j < len(cur); j++ {
+				if !match(cur[j].record.RR(), want[i].record.RR()) {
+					continue
+				}
+				if pass > 0 {
+					changes = append(changes, cur[j].change(SyncUpdate, want[i].record))
+				}
+				cur = append(cur[:j], cur[j+1:]...)
+				want = append(want[:i], want[i+1:]...)
+				i--
+				break
+			}
+		}
+	}
+
+	// update remaining records in place, then create or delete the rest
+	for len(cur) > 0 && len(want) > 0 {
+		changes = append(changes, cur[0].change(SyncUpdate, want[0].record))
+		cur, want = cur[1:], want[1:]
+	}
+	for _, w := range want {
+		changes = append(changes, SyncChange{Action: SyncCreate, New: w.record})
+	}
+	for _, c := range cur {
+		changes = append(changes, c.change(SyncDelete, nil))
+	}
+	return changes
+}
+
+// change returns the update of the current record r to desired, or its
+// deletion if desired is nil.
+func (r syncRecord) change(action SyncAction, desired libdns.Record) SyncChange {
+	return SyncChange{Action: action, RecordID: r.id, ChangeDate: r.changeDate, Old: r.record, New: desired}
+}
+
+// ApplySync executes the changes of plan. Updates are made first, then
+// records are created, and deleted last, so that names do not disappear in
+// between. ApplySync stops at the first error and returns the changes that
+// were made until then.
+//
+// If a record to be updated or deleted was changed or deleted since the
+// plan was made, ApplySync returns a *ConflictError or an error wrapping
+// ErrRecordNotFound, respectively, before making any change. The plan must
+// then be made again. The TTLs of created and updated records are
+// normalized by p.TTLPolicy, like in PlanSync.
+func (p *Provider) ApplySync(ctx context.Context, plan *SyncPlan) ([]SyncChange, error) {
+	changes := make([]SyncChange, len(plan.Changes))
+	for i, c := range plan.Changes {
+		if c.New != nil {
+			r, err := p.applyTTL(c.New)
+			if err != nil {
+				return nil, err
+			}
+			c.New = r
+		}
+		changes[i] = c
+	}
+
+	ctx, unlock, err := p.lockZone(ctx, zoneDescriptor{ID: plan.zoneID, Name: plan.Zone})
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := p.checkSyncChanges(plan); err != nil {
+		return nil, err
+	}
+	if err := p.checkSyncStale(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	var applied []SyncChange
+
+	for _, c := range changes {
+		if c.Action != SyncUpdate {
+			continue
+		}
+		if err := ionosUpdateRecord(ctx, p.client(), plan.zoneID, c.RecordID, toIonosRecord(c.New, plan.Zone)); err != nil {
+			return applied, fmt.Errorf("update record %s: %w", c.RecordID, err)
+		}
+		applied = append(applied, c)
+	}
+
+	var creates []SyncChange
+	var reqs []record
+	for _, c := range changes {
+		if c.Action == SyncCreate {
+			creates = append(creates, c)
+			reqs = append(reqs, toIonosRecord(c.New, plan.Zone))
+		}
+	}
+	if len(reqs) > 0 {
+		created, err := ionosCreateRecords(ctx, p.client(), plan.zoneID, reqs)
+		if err != nil {
+			return applied, fmt.Errorf("create records: %w", err)
+		}
+		for i, c := range creates {
+			if i < len(created) {
+				c.RecordID = created[i].ID
+			}
+			applied = append(applied, c)
+		}
+	}
+
+	for _, c := range changes {
+		if c.Action != SyncDelete {
+			continue
+		}
+		if err := ionosDeleteRecord(ctx, p.client(), plan.zoneID, c.RecordID); err != nil {
+			return applied, fmt.Errorf("delete record %s: %w", c.RecordID, err)
+		}
+		applied = append(applied, c)
+	}
+	return applied, nil
+}
+
+// checkSyncChanges checks the updates and deletes of plan against p.Guard.
+func (p *Provider) checkSyncChanges(plan *SyncPlan) error {
+	for _, c := range plan.Changes {
+		if c.Action == SyncCreate {
+			continue
+		}
+		rr := c.Old.RR()
+		if err := p.Guard.checkChange(plan.Zone, libdns.AbsoluteName(rr.Name, plan.Zone), rr.Type); err != nil {
+			return err
+		}
+	}
+	return p.Guard.checkDeletions(plan.Zone, plan.Count(SyncDelete))
+}
+
+// checkSyncStale checks that the records updated or deleted by plan were
+// not changed since the plan was made.
+func (p *Provider) checkSyncStale(ctx context.Context, plan *SyncPlan) error {
+	if plan.Count(SyncUpdate)+plan.Count(SyncDelete) == 0 {
+		return nil
+	}
+	resp, err := ionosGetZone(ctx, p.client(), plan.zoneID, "", "", "")
+	if err != nil {
+		return fmt.Errorf("get zone records: %w", err)
+	}
+	current := make(map[string]zoneRecord, len(resp.Records))
+	for _, zr := range resp.Records {
+		current[zr.ID] = zr
+	}
+	for _, c := range plan.Changes {
+		if c.Action == SyncCreate {
+			continue
+		}
+		zr, ok := current[c.RecordID]
+		if !ok {
+			return fmt.Errorf("record %s: %w", c.RecordID, ErrRecordNotFound)
+		}
+		changeDate, _ := time.Parse(time.RFC3339Nano, zr.ChangeDate)
+		if !changeDate.Equal(c.ChangeDate) {
+			return &ConflictError{ID: c.RecordID, Expected: c.ChangeDate, ChangeDate: changeDate}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_PlanAndApplySync(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "@", "NS", "ns1.ui-dns.de", 86400)
+	keep := fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+	ttlChange := fs.addRecord(zone, "www", "A", "1.2.3.5", 300)
+	dataChange := fs.addRecord(zone, "api", "A", "1.2.3.6", 300)
+	fs.addRecord(zone, "old", "TXT", "remove me", 300)
+
+	desired := []libdns.Record{
+		libdns.Address{Name: "www", IP: netip.MustParseAddr("1.2.3.4"), TTL: 0},
+		libdns.Address{Name: "www", IP: netip.MustParseAddr("1.2.3.5"), TTL: time.Hour},
+		libdns.Address{Name: "api", IP: netip.MustParseAddr("1.2.3.7"), TTL: 5 * time.Minute},
+		libdns.TXT{Name: "new", Text: "hello", TTL: 5 * time.Minute},
+	}
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	plan, err := p.PlanSync(context.TODO(), "example.com", desired, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `zone example.com: 1 to create, 2 to update, 1 to delete
+~ api	300	IN	A	1.2.3.6
+  => api	300	IN	A	1.2.3.7
++ new	300	IN	TXT	"hello"
+- old	300	IN	TXT	"remove me"
+~ www	300	IN	A	1.2.3.5
+  => www	3600	IN	A	1.2.3.5
+`
+	if plan.String() != want {
+		t.Fatalf("unexpected plan:\n%s\nexpected:\n%s", plan, want)
+	}
+	if n := fs.requestCount("PUT") + fs.requestCount("POST") + fs.requestCount("DELETE"); n != 0 {
+		t.Fatalf("expected PlanSync to make no changes, got %d", n)
+	}
+
+	applied, err := p.ApplySync(context.TODO(), plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 4 {
+		t.Fatalf("expected 4 changes to be applied, got %d", len(applied))
+	}
+
+	byID := make(map[string]zoneRecord)
+	for _, r := range fs.records(zone) {
+		byID[r.ID] = r
+	}
+	if len(byID) != 5 {
+		t.Fatalf("expected 5 records after sync, got %d", len(byID))
+	}
+	if r, ok := byID[keep.ID]; !ok || r.ChangeDate != keep.ChangeDate {
+		t.Errorf("expected record %s to be untouched", keep.ID)
+	}
+	if r := byID[ttlChange.ID]; r.TTL != 3600 {
+		t.Errorf("expected TTL of record %s to be updated in place, got %+v", ttlChange.ID, r)
+	}
+	if r := byID[dataChange.ID]; r.Content != "1.2.3.7" {
+		t.Errorf("expected content of record %s to be updated in place, got %+v", dataChange.ID, r)
+	}
+
+	// the zone is in sync now
+	plan, err = p.PlanSync(context.TODO(), "example.com", desired, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Fatalf("expected empty plan, got\n%s", plan)
+	}
+}
+
+func Test_PlanSyncTrailingDot(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "www", "CNAME", "target.example.net", 300)
+	txt := fs.addRecord(zone, "txt", "TXT", "foo", 300)
+
+	// target names are compared without trailing dot, TXT data exactly
+	desired := []libdns.Record{
+		libdns.CNAME{Name: "www", Target: "target.example.net.", TTL: 5 * time.Minute},
+		libdns.TXT{Name: "txt", Text: "foo.", TTL: 5 * time.Minute},
+	}
+	p := &Provider{AuthAPIToken: "public.secret"}
+	plan, err := p.PlanSync(context.TODO(), "example.com", desired, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Action != SyncUpdate || plan.Changes[0].RecordID != txt.ID {
+		t.Fatalf("expected only the TXT record to be updated, got\n%s", plan)
+	}
+}
+
+func Test_ApplySyncRefusesStalePlan(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	www := fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+	old := fs.addRecord(zone, "old", "TXT", "remove me", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	desired := []libdns.Record{
+		libdns.Address{Name: "www", IP: netip.MustParseAddr("1.2.3.5"), TTL: 5 * time.Minute},
+		libdns.TXT{Name: "new", Text: "hello", TTL: 5 * time.Minute},
+	}
+	plan, err := p.PlanSync(context.TODO(), "example.com", desired, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// www is changed by someone else after the plan was made
+	fs.mu.Lock()
+	r := zone.records[www.ID]
+	r.Content = "1.2.3.6"
+	r.ChangeDate = time.Now().Add(time.Second).UTC().Format(time.RFC3339Nano)
+	zone.records[www.ID] = r
+	fs.mu.Unlock()
+
+	var conflict *ConflictError
+	if applied, err := p.ApplySync(context.TODO(), plan); !errors.As(err, &conflict) || conflict.ID != www.ID || len(applied) != 0 {
+		t.Fatalf("expected a *ConflictError before any change, got %v, %v", applied, err)
+	}
+
+	// old is deleted by someone else
+	plan, err = p.PlanSync(context.TODO(), "example.com", desired, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.mu.Lock()
+	delete(zone.records, old.ID)
+	fs.mu.Unlock()
+	if applied, err := p.ApplySync(context.TODO(), plan); !errors.Is(err, ErrRecordNotFound) || len(applied) != 0 {
+		t.Fatalf("expected ErrRecordNotFound before any change, got %v, %v", applied, err)
+	}
+	if n := fs.requestCount("PUT") + fs.requestCount("POST") + fs.requestCount("DELETE"); n != 0 {
+		t.Fatalf("expected no changes, got %d", n)
+	}
+}
+
+func Test_PlanSyncSafety(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "_dmarc", "TXT", "v=DMARC1; p=reject", 300)
+	fs.addRecord(zone, "a", "A", "1.2.3.4", 300)
+	fs.addRecord(zone, "b", "A", "1.2.3.4", 300)
+	fs.addRecord(zone, "s1._domainkey", "TXT", "k=rsa", 300)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	opts := SyncOptions{ProtectedNames: []string{"_dmarc", "*._domainkey"}}
+	plan, err := p.PlanSync(context.TODO(), "example.com", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Count(SyncDelete) != 2 || len(plan.Protected) != 2 {
+		t.Fatalf("expected 2 deletions and 2 protected records, got\n%s", plan)
+	}
+	if !strings.Contains(plan.String(), "! _dmarc\t300\tIN\tTXT") {
+		t.Fatalf("expected protected record in plan, got\n%s", plan)
+	}
+
+	opts.MaxDeletions = 1
+	if _, err := p.PlanSync(context.TODO(), "example.com", nil, opts); err == nil {
+		t.Fatal("expected error for too many deletions")
+	}
+}
+
+func Test_isProtectedName(t *testing.T) {
+	patterns := []string{"@", "_DMARC", "*._domainkey", "Bücher", `\*.sub`}
+	for _, tc := range []struct {
+		name     string
+		expected bool
+	}{
+		{"@", true},
+		{"_dmarc", true},
+		{"s1._domainkey", true},
+		{"xn--bcher-kva", true},
+		{"*.sub", true},
+		{"www.sub", false},
+		{"www", false},
+	} {
+		if got := isProtectedName(tc.name, patterns); got != tc.expected {
+			t.Errorf("isProtectedName(%q) = %v, expected %v", tc.name, got, tc.expected)
+		}
+	}
+}
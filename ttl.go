@@ -0,0 +1,152 @@
+// TTL normalization policy
+package ionos
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ionosDefaultTTL is the TTL in seconds IONOS applies if none is given.
+const ionosDefaultTTL = 3600
+
+// TTLMode determines how a TTLPolicy handles TTLs IONOS does not accept.
+type TTLMode string
+
+const (
+	// TTLReject rejects TTLs below 60 seconds with ErrTTLTooLow. This is
+	// the default.
+	TTLReject TTLMode = "reject"
+	// TTLClamp raises TTLs below 60 seconds to 60 seconds.
+	TTLClamp TTLMode = "clamp"
+	// TTLRound replaces every TTL by the nearest of AllowedTTLs.
+	TTLRound TTLMode = "round"
+)
+
+// AllowedTTLs are the TTLs offered by IONOS, in ascending order.
+var AllowedTTLs = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	2 * time.Hour,
+	4 * time.Hour,
+	8 * time.Hour,
+	12 * time.Hour,
+	24 * time.Hour,
+}
+
+// TTLPolicy normalizes the TTLs of records before they are sent to IONOS.
+// TTLs are always rounded to whole seconds.
+type TTLPolicy struct {
+	Mode TTLMode `json:"mode,omitempty"`
+	// Default replaces a TTL of 0. If Default is 0 as well, the TTL is
+	// left to IONOS, which applies 1 hour.
+	Default time.Duration `json:"default,omitempty"`
+}
+
+// Apply returns the TTL to use for ttl. In TTLReject mode, TTLs below 60
+// seconds are rejected with an error wrapping ErrTTLTooLow.
+func (pol TTLPolicy) Apply(ttl time.Duration) (time.Duration, error) {
+	if ttl == 0 {
+		if pol.Default == 0 {
+			return 0, nil
+		}
+		ttl = pol.Default
+	}
+	ttl = ttl.Round(time.Second)
+
+	switch pol.Mode {
+	case "", TTLReject:
+		if ttl < minTTL {
+			return 0, fmt.Errorf("%w: %v", ErrTTLTooLow, ttl)
+		}
+		return ttl, nil
+	case TTLClamp:
+		if ttl < minTTL {
+			ttl = minTTL
+		}
+		return ttl, nil
+	case TTLRound:
+		return nearestTTL(ttl), nil
+	default:
+		return 0, fmt.Errorf("unknown TTL mode %q", pol.Mode)
+	}
+}
+
+// nearestTTL returns the element of AllowedTTLs closest to ttl, the larger
+// one if two are equally close.
+func nearestTTL(ttl time.Duration) time.Duration {
+	best := AllowedTTLs[0]
+	for _, allowed := range AllowedTTLs[1:] {
+		if absDuration(allowed-ttl) <= absDuration(best-ttl) {
+			best = allowed
+		}
+	}
+	return best
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// applyTTLPolicy returns records with the TTLs of p.TTLPolicy applied.
+// Rejected TTLs of all records are reported in one *ValidationError.
+func (p *Provider) applyTTLPolicy(records []libdns.Record) ([]libdns.Record, error) {
+	result := make([]libdns.Record, len(records))
+	var verr ValidationError
+	for i, r := range records {
+		var err error
+		result[i], err = p.applyTTL(r)
+		var rerr *ValidationError
+		if errors.As(err, &rerr) {
+			verr.Errors = append(verr.Errors, rerr.Errors...)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(verr.Errors) > 0 {
+		return nil, &verr
+	}
+	return result, nil
+}
+
+// applyTTL returns r with the TTL of p.TTLPolicy applied. A rejected TTL is
+// reported as *ValidationError.
+func (p *Provider) applyTTL(r libdns.Record) (libdns.Record, error) {
+	ttl := r.RR().TTL
+	applied, err := p.TTLPolicy.Apply(ttl)
+	if errors.Is(err, ErrTTLTooLow) {
+		return nil, &ValidationError{Errors: []*RecordError{{Record: r, Err: err}}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if applied != ttl {
+		return withTTL(r, applied), nil
+	}
+	return r, nil
+}
+
+// applyTTLSeconds returns the TTL in seconds of p.TTLPolicy for a TTL in
+// seconds, as stored in snapshots.
+func (p *Provider) applyTTLSeconds(ttl int) (int, error) {
+	applied, err := p.TTLPolicy.Apply(time.Duration(ttl) * time.Second)
+	if err != nil || applied == 0 {
+		return ttl, err
+	}
+	return int(applied / time.Second), nil
+}
+
+// withTTL returns a copy of r with the given TTL.
+func withTTL(r libdns.Record, ttl time.Duration) libdns.Record {
+	return modifyRR(r, func(rr *libdns.RR) { rr.TTL = ttl })
+}
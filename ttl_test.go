@@ -0,0 +1,186 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_TTLPolicyApply(t *testing.T) {
+	tests := []struct {
+		policy TTLPolicy
+		ttl    time.Duration
+		want   time.Duration
+	}{
+		{TTLPolicy{}, 0, 0},
+		{TTLPolicy{}, 90*time.Second + 600*time.Millisecond, 91 * time.Second},
+		{TTLPolicy{Default: 5 * time.Minute}, 0, 5 * time.Minute},
+		{TTLPolicy{Mode: TTLClamp}, 30 * time.Second, time.Minute},
+		{TTLPolicy{Mode: TTLClamp}, 90 * time.Second, 90 * time.Second},
+		{TTLPolicy{Mode: TTLClamp, Default: time.Second}, 0, time.Minute},
+		{TTLPolicy{Mode: TTLRound}, 30 * time.Second, time.Minute},
+		{TTLPolicy{Mode: TTLRound}, 90 * time.Second, time.Minute},
+		{TTLPolicy{Mode: TTLRound}, 3 * time.Minute, 5 * time.Minute},
+		{TTLPolicy{Mode: TTLRound}, 45 * time.Minute, time.Hour},
+		{TTLPolicy{Mode: TTLRound}, 7 * 24 * time.Hour, 24 * time.Hour},
+		{TTLPolicy{Mode: TTLRound}, 0, 0},
+	}
+	for _, tt := range tests {
+		got, err := tt.policy.Apply(tt.ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%+v.Apply(%v): expected %v, got %v", tt.policy, tt.ttl, tt.want, got)
+		}
+	}
+	if _, err := (TTLPolicy{}).Apply(30 * time.Second); !errors.Is(err, ErrTTLTooLow) {
+		t.Fatalf("expected ErrTTLTooLow, got %v", err)
+	}
+	if _, err := (TTLPolicy{Mode: "bogus"}).Apply(time.Minute); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+}
+
+func Test_TTLPolicyInProvider(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "www", "A", "1.2.3.4", 600)
+
+	records := []libdns.Record{libdns.TXT{Name: "txt", Text: "x", TTL: 30 * time.Second}}
+
+	// rejected by default
+	p := &Provider{AuthAPIToken: "public.secret"}
+	if _, err := p.AppendRecords(context.TODO(), "example.com", records); !errors.Is(err, ErrTTLTooLow) {
+		t.Fatalf("expected ErrTTLTooLow, got %v", err)
+	}
+
+	// clamped, and returned with the TTL applied
+	p.TTLPolicy = TTLPolicy{Mode: TTLClamp}
+	set, err := p.SetRecords(context.TODO(), "example.com", records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (libdns.TXT{Name: "txt", Text: "x", TTL: time.Minute}); len(set) != 1 || set[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, set)
+	}
+
+	// updates without TTL keep the TTL of the record, unless a default
+	// is configured
+	set, err = p.SetRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "1.2.3.5"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 1 || set[0].RR().TTL != 10*time.Minute {
+		t.Fatalf("expected TTL of existing record, got %+v", set)
+	}
+	p.TTLPolicy.Default = 2 * time.Hour
+	set, err = p.SetRecords(context.TODO(), "example.com", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "1.2.3.6"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 1 || set[0].RR().TTL != 2*time.Hour {
+		t.Fatalf("expected default TTL, got %+v", set)
+	}
+
+	for _, r := range fs.records(zone) {
+		if r.TTL < 60 {
+			t.Errorf("record %+v sent with TTL below minimum", r)
+		}
+	}
+}
+
+func Test_TTLPolicyRejectsBeforeRequests(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	www := fs.addRecord(zone, "www", "A", "1.2.3.4", 600)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	desired := []libdns.Record{libdns.TXT{Name: "txt", Text: "x", TTL: 30 * time.Second}}
+	var verr *ValidationError
+	if _, err := p.PlanSync(context.TODO(), "example.com", desired, SyncOptions{}); !errors.As(err, &verr) || !errors.Is(err, ErrTTLTooLow) {
+		t.Fatalf("expected ValidationError with ErrTTLTooLow, got %v", err)
+	}
+	if _, err := p.UpdateRecordByID(context.TODO(), "example.com", www.ID,
+		libdns.RR{Name: "www", Type: "A", Data: "1.2.3.5", TTL: 30 * time.Second}, time.Time{}); !errors.Is(err, ErrTTLTooLow) {
+		t.Fatalf("expected ErrTTLTooLow, got %v", err)
+	}
+	if n := fs.requestCount("GET") + fs.requestCount("PUT") + fs.requestCount("POST"); n != 0 {
+		t.Fatalf("expected no request, got %d", n)
+	}
+}
+
+func Test_TTLPolicyInSyncAndUpdates(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	www := fs.addRecord(zone, "www", "A", "1.2.3.4", 600)
+
+	p := &Provider{AuthAPIToken: "public.secret", TTLPolicy: TTLPolicy{Mode: TTLRound}}
+	desired := []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "1.2.3.4", TTL: 7 * time.Minute},
+		libdns.TXT{Name: "txt", Text: "x", TTL: 30 * time.Second},
+	}
+	plan, err := p.PlanSync(context.TODO(), "example.com", desired, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ApplySync(context.TODO(), plan); err != nil {
+		t.Fatal(err)
+	}
+	// the zone converges
+	plan, err = p.PlanSync(context.TODO(), "example.com", desired, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Fatalf("expected empty plan, got\n%s", plan)
+	}
+
+	updated, err := p.UpdateRecordByID(context.TODO(), "example.com", www.ID,
+		libdns.RR{Name: "www", Type: "A", Data: "1.2.3.5", TTL: 50 * time.Minute}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := updated.RR().TTL; ttl != time.Hour {
+		t.Fatalf("expected rounded TTL, got %v", ttl)
+	}
+
+	snap, err := p.Snapshot(context.TODO(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range snap.Records {
+		snap.Records[i].TTL = 20
+	}
+	if _, err := p.Restore(context.TODO(), "example.com", snap); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range fs.records(zone) {
+		if r.TTL != 60 && r.TTL != 300 && r.TTL != 3600 {
+			t.Errorf("record %+v sent with a TTL IONOS does not offer", r)
+		}
+	}
+}
+
+func Test_withTTL(t *testing.T) {
+	txt := withTTL(libdns.TXT{Name: "a", Text: "x"}, time.Minute)
+	if txt != (libdns.TXT{Name: "a", Text: "x", TTL: time.Minute}) {
+		t.Fatalf("unexpected TXT %+v", txt)
+	}
+	rr := withTTL(libdns.RR{Name: "a", Type: "A", Data: "1.2.3.4"}, time.Minute)
+	if rr != (libdns.RR{Name: "a", Type: "A", Data: "1.2.3.4", TTL: time.Minute}) {
+		t.Fatalf("unexpected RR %+v", rr)
+	}
+	rec := withTTL(Record{Record: libdns.TXT{Name: "a", Text: "x"}, ID: "id"}, time.Minute)
+	if r, ok := rec.(Record); !ok || r.ID != "id" || r.RR().TTL != time.Minute {
+		t.Fatalf("unexpected Record %+v", rec)
+	}
+}
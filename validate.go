@@ -0,0 +1,132 @@
+// validation of records against IONOS constraints
+package ionos
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// Constraints of records checked by ValidateRecords.
+var (
+	ErrMissingType    = errors.New("missing record type")
+	ErrTTLTooLow      = errors.New("TTL below 60 seconds")
+	ErrInvalidName    = errors.New("invalid name")
+	ErrInvalidAddress = errors.New("invalid IP address")
+	ErrCNAMEAtApex    = errors.New("CNAME at zone apex")
+	ErrCNAMEConflict  = errors.New("CNAME with other records of the same name")
+)
+
+// minTTL is the lowest TTL accepted by IONOS.
+const minTTL = 60 * time.Second
+
+// Limits of domain names, see RFC 1035 section 2.3.4.
+const (
+	maxLabelLength = 63
+	maxNameLength  = 253
+)
+
+// ValidationError lists the records violating IONOS constraints. The error
+// of each record wraps one of the Err* values of this package, e.g.
+// ErrTTLTooLow.
+type ValidationError struct {
+	// Errors are the errors of the invalid records, in input order. A
+	// record may have several errors.
+	Errors []*RecordError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d invalid record(s): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the invalid records.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ValidateRecords checks records for the zone against the constraints known
+// to be enforced by IONOS, so that invalid input is rejected with a precise
+// error instead of a bare 400 from the API. It returns a *ValidationError
+// if any record is invalid.
+//
+// A CNAME conflicting with other records of the same name is only detected
+// within records; conflicts with records already in the zone are reported
+// by IONOS.
+func ValidateRecords(zone string, records []libdns.Record) error {
+	var verr ValidationError
+	fail := func(r libdns.Record, err error) {
+		verr.Errors = append(verr.Errors, &RecordError{Record: r, Err: err})
+	}
+
+	zone = canonicalName(zone)
+
+	// number of records by name, to find CNAME conflicts
+	count := make(map[string]int)
+	for _, r := range records {
+		count[canonicalName(libdns.AbsoluteName(r.RR().Name, zone))]++
+	}
+
+	for _, r := range records {
+		rr := r.RR()
+		typ := strings.ToUpper(rr.Type)
+		name := canonicalName(libdns.AbsoluteName(rr.Name, zone))
+
+		if typ == "" {
+			fail(r, ErrMissingType)
+		}
+		if rr.TTL != 0 && rr.TTL < minTTL {
+			fail(r, fmt.Errorf("%w: %v", ErrTTLTooLow, rr.TTL))
+		}
+		if err := validateName(name); err != nil {
+			fail(r, err)
+		}
+
+		switch typ {
+		case "A", "AAAA":
+			ip, err := netip.ParseAddr(rr.Data)
+			if err != nil || (typ == "A") != ip.Is4() || ip.Zone() != "" {
+				fail(r, fmt.Errorf("%w for %s record: %q", ErrInvalidAddress, typ, rr.Data))
+			}
+		case "CNAME":
+			if name == zone {
+				fail(r, ErrCNAMEAtApex)
+			} else if n := count[name]; n > 1 {
+				fail(r, fmt.Errorf("%w: %d records named %s", ErrCNAMEConflict, n, name))
+			}
+		}
+	}
+
+	if len(verr.Errors) > 0 {
+		return &verr
+	}
+	return nil
+}
+
+// validateName checks the length of name and its labels.
+func validateName(name string) error {
+	name = unFQDN(name)
+	if len(name) > maxNameLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidName, maxNameLength)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return fmt.Errorf("%w: empty label in %q", ErrInvalidName, name)
+		}
+		if len(label) > maxLabelLength {
+			return fmt.Errorf("%w: label of %d characters in %q, at most %d allowed", ErrInvalidName, len(label), name, maxLabelLength)
+		}
+	}
+	return nil
+}
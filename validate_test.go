@@ -0,0 +1,90 @@
+package ionos
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_ValidateRecords(t *testing.T) {
+	long := strings.Repeat("a", 64)
+	tests := []struct {
+		name    string
+		records []libdns.Record
+		want    []error // per error, in order
+	}{
+		{"valid", []libdns.Record{
+			libdns.Address{Name: "www", IP: netip.MustParseAddr("1.2.3.4")},
+			libdns.RR{Name: "v6", Type: "AAAA", Data: "2001:db8::1", TTL: time.Minute},
+			libdns.CNAME{Name: "alias", Target: "www.example.com."},
+			libdns.TXT{Name: "@", Text: "hello"},
+		}, nil},
+		{"ttl", []libdns.Record{libdns.TXT{Name: "txt", Text: "x", TTL: 30 * time.Second}}, []error{ErrTTLTooLow}},
+		{"long label", []libdns.Record{libdns.TXT{Name: long, Text: "x"}}, []error{ErrInvalidName}},
+		{"empty label", []libdns.Record{libdns.TXT{Name: "a..b", Text: "x"}}, []error{ErrInvalidName}},
+		{"bad ipv4", []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "1.2.3"}}, []error{ErrInvalidAddress}},
+		{"ipv6 in A", []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "2001:db8::1"}}, []error{ErrInvalidAddress}},
+		{"ipv4 in AAAA", []libdns.Record{libdns.RR{Name: "www", Type: "AAAA", Data: "1.2.3.4"}}, []error{ErrInvalidAddress}},
+		{"missing type", []libdns.Record{libdns.RR{Name: "www", Data: "x"}}, []error{ErrMissingType}},
+		{"cname at apex", []libdns.Record{libdns.CNAME{Name: "@", Target: "other.example."}}, []error{ErrCNAMEAtApex}},
+		{"cname conflict", []libdns.Record{
+			libdns.CNAME{Name: "www", Target: "other.example."},
+			libdns.TXT{Name: "WWW", Text: "x"},
+		}, []error{ErrCNAMEConflict}},
+		{"several", []libdns.Record{
+			libdns.RR{Name: long, Type: "A", Data: "bad", TTL: time.Second},
+			libdns.TXT{Name: "ok", Text: "x"},
+			libdns.CNAME{Name: "", Target: "other.example."},
+		}, []error{ErrTTLTooLow, ErrInvalidName, ErrInvalidAddress, ErrCNAMEAtApex}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRecords("example.com.", tt.records)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected ValidationError, got %v", err)
+			}
+			if len(verr.Errors) != len(tt.want) {
+				t.Fatalf("expected %d errors, got %v", len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				if !errors.Is(verr.Errors[i], want) {
+					t.Errorf("error %d: expected %v, got %v", i, want, verr.Errors[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_InvalidRecordsAreNotSent(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("public.secret", "example.com")
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	records := []libdns.Record{
+		libdns.TXT{Name: "ok", Text: "x"},
+		libdns.RR{Name: "www", Type: "A", Data: "not-an-ip"},
+	}
+	if _, err := p.AppendRecords(context.TODO(), "example.com", records); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("expected ErrInvalidAddress from AppendRecords, got %v", err)
+	}
+	if _, err := p.SetRecords(context.TODO(), "example.com", records); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("expected ErrInvalidAddress from SetRecords, got %v", err)
+	}
+	for _, method := range []string{"GET", "POST", "PUT"} {
+		if n := fs.requestCount(method); n != 0 {
+			t.Errorf("expected no %s requests, got %d", method, n)
+		}
+	}
+}
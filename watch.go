@@ -0,0 +1,177 @@
+// polling for record changes
+package ionos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// RecordEventType is the kind of a RecordEvent.
+type RecordEventType string
+
+const (
+	RecordAdded   RecordEventType = "added"
+	RecordChanged RecordEventType = "changed"
+	RecordRemoved RecordEventType = "removed"
+	// WatchError events report a failed poll. Watch keeps polling, with
+	// increasing delay.
+	WatchError RecordEventType = "error"
+)
+
+// maxWatchBackoff limits the delay between polls after errors, as a multiple
+// of the poll interval.
+const maxWatchBackoff = 16
+
+// RecordEvent describes a change of a record found by Watch.
+type RecordEvent struct {
+	Type RecordEventType
+	// ID is the IONOS ID of the record.
+	ID string
+	// ChangeDate is the time of the change as reported by IONOS. It is
+	// zero for removed records, since IONOS does not report deletions.
+	ChangeDate time.Time
+	// Record is the current state of the record, or the last known state
+	// for removed records.
+	Record libdns.Record
+	// Old is the previous state of changed records.
+	Old libdns.Record
+	// Err is set for WatchError events.
+	Err error
+}
+
+// Watch polls zone every interval and sends an event for each record that
+// was added, changed or removed since the previous poll. Records are
+// compared by their IONOS ID and change date. The first poll only
+// establishes the baseline; if it fails, Watch returns the error.
+//
+// Failed polls are reported as WatchError events, and the delay until the
+// next poll is doubled, up to 16 times the interval. The channel is closed
+// when ctx is cancelled.
+func (p *Provider) Watch(ctx context.Context, zone string, interval time.Duration) (<-chan RecordEvent, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid watch interval %v", interval)
+	}
+	zoneDes, err := p.findZoneByName(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("find zone: %w", err)
+	}
+	known, err := p.pollZone(ctx, zoneDes)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan RecordEvent)
+	go func() {
+		defer close(events)
+		delay := interval
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			current, err := p.pollZone(ctx, zoneDes)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if !sendEvent(ctx, events, RecordEvent{Type: WatchError, Err: err}) {
+					return
+				}
+				if delay < maxWatchBackoff*interval {
+					delay *= 2
+				}
+				continue
+			}
+			delay = interval
+
+			for _, ev := range diffPolls(known, current, zoneDes.Name) {
+				ev.Record = p.outputRecord(ev.Record)
+				ev.Old = p.outputRecord(ev.Old)
+				if !sendEvent(ctx, events, ev) {
+					return
+				}
+			}
+			known = current
+		}
+	}()
+	return events, nil
+}
+
+func sendEvent(ctx context.Context, events chan<- RecordEvent, ev RecordEvent) bool {
+	select {
+	case events <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// pollZone returns the records of the zone by ID.
+func (p *Provider) pollZone(ctx context.Context, zoneDes zoneDescriptor) (map[string]zoneRecord, error) {
+	resp, err := ionosGetZone(ctx, p.client(), zoneDes.ID, "", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("get zone records: %w", err)
+	}
+	records := make(map[string]zoneRecord, len(resp.Records))
+	for _, r := range resp.Records {
+		records[r.ID] = r
+	}
+	return records, nil
+}
+
+// diffPolls returns the events for the differences between two polls,
+// sorted by record ID.
+func diffPolls(previous, current map[string]zoneRecord, zoneName string) []RecordEvent {
+	var events []RecordEvent
+	for id, cur := range current {
+		prev, ok := previous[id]
+		switch {
+		case !ok:
+			events = append(events, newRecordEvent(RecordAdded, cur, nil, zoneName))
+		case prev.ChangeDate != cur.ChangeDate || prev != cur:
+			old := watchRecord(prev, zoneName)
+			events = append(events, newRecordEvent(RecordChanged, cur, old, zoneName))
+		}
+	}
+	for id, prev := range previous {
+		if _, ok := current[id]; !ok {
+			ev := newRecordEvent(RecordRemoved, prev, nil, zoneName)
+			ev.ChangeDate = time.Time{}
+			events = append(events, ev)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events
+}
+
+func newRecordEvent(typ RecordEventType, r zoneRecord, old libdns.Record, zoneName string) RecordEvent {
+	changeDate, _ := time.Parse(time.RFC3339Nano, r.ChangeDate)
+	return RecordEvent{
+		Type:       typ,
+		ID:         r.ID,
+		ChangeDate: changeDate,
+		Record:     watchRecord(r, zoneName),
+		Old:        old,
+	}
+}
+
+// watchRecord converts r to a libdns record. Records which can not be
+// parsed are returned as libdns.RR, so that no change gets lost.
+func watchRecord(r zoneRecord, zoneName string) libdns.Record {
+	result, err := fromIonosRecord(r, zoneName)
+	if err != nil {
+		return libdns.RR{
+			Name: relativeName(r.Name, zoneName),
+			TTL:  time.Duration(r.TTL) * time.Second,
+			Type: r.Type,
+			Data: r.Content,
+		}
+	}
+	return result
+}
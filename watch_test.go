@@ -0,0 +1,124 @@
+package ionos
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_Watch(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	changed := fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+	removed := fs.addRecord(zone, "old", "TXT", "bye", 300)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	p := &Provider{AuthAPIToken: "public.secret"}
+	events, err := p.Watch(ctx, "example.com", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs.mu.Lock()
+	delete(zone.records, removed.ID)
+	r := zone.records[changed.ID]
+	r.Content = "1.2.3.5"
+	r.ChangeDate = time.Now().Add(time.Second).UTC().Format(time.RFC3339Nano)
+	zone.records[changed.ID] = r
+	fs.mu.Unlock()
+	added := fs.addRecord(zone, "new", "A", "1.2.3.6", 300)
+
+	want := map[string]RecordEventType{
+		changed.ID: RecordChanged,
+		removed.ID: RecordRemoved,
+		added.ID:   RecordAdded,
+	}
+	timeout := time.After(5 * time.Second)
+	for len(want) > 0 {
+		select {
+		case ev := <-events:
+			if ev.Type == WatchError {
+				t.Fatal(ev.Err)
+			}
+			typ, ok := want[ev.ID]
+			if !ok || typ != ev.Type {
+				t.Fatalf("unexpected event %+v", ev)
+			}
+			delete(want, ev.ID)
+			switch ev.Type {
+			case RecordChanged:
+				if ev.Old.RR().Data != "1.2.3.4" || ev.Record.RR().Data != "1.2.3.5" {
+					t.Errorf("expected change from 1.2.3.4 to 1.2.3.5, got %+v", ev)
+				}
+			case RecordRemoved:
+				if ev.Record.RR().Data != "bye" || !ev.ChangeDate.IsZero() {
+					t.Errorf("expected removed record with last known state, got %+v", ev)
+				}
+			case RecordAdded:
+				if ev.Record.RR().Name != "new" || ev.ChangeDate.IsZero() {
+					t.Errorf("expected added record with change date, got %+v", ev)
+				}
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for events %v", want)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func Test_WatchReportsErrors(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("public.secret", "example.com")
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	p := &Provider{AuthAPIToken: "public.secret"}
+	events, err := p.Watch(ctx, "example.com", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the token owns no zone anymore, so the fake server answers 401
+	fs.mu.Lock()
+	fs.zones = map[string]*fakeZone{}
+	fs.mu.Unlock()
+
+	select {
+	case ev := <-events:
+		if ev.Type != WatchError || ev.Err == nil {
+			t.Fatalf("expected error event, got %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for error event")
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func Test_WatchUnknownZone(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.addZone("public.secret", "example.com")
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	if _, err := p.Watch(context.TODO(), "example.org", time.Second); err == nil {
+		t.Fatal("expected error for unknown zone")
+	}
+}
+
+func Test_watchRecordUnparsable(t *testing.T) {
+	// the zone name is given as by the user, the record name as by IONOS
+	r := zoneRecord{Name: "www.example.com", Type: "A", Content: "not an address", TTL: 300}
+	rr := watchRecord(r, "Example.COM.").RR()
+	if rr.Name != "www" || rr.Data != "not an address" {
+		t.Fatalf("unexpected record %+v", rr)
+	}
+	if rr := watchRecord(zoneRecord{Name: "example.com", Type: "A", Content: "x"}, "example.com").RR(); rr.Name != "@" {
+		t.Fatalf("expected the apex as @, got %+v", rr)
+	}
+}
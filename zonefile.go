@@ -0,0 +1,179 @@
+// RFC 1035 zone file export
+package ionos
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ExportZone writes all records of zone as a BIND-style master file (RFC
+// 1035, section 5) to w. See WriteZoneFile for the format. Names are
+// written as A-labels, regardless of p.UnicodeNames.
+func (p *Provider) ExportZone(ctx context.Context, zone string, w io.Writer) error {
+	records, err := p.GetRecords(ctx, zone)
+	if err != nil {
+		return err
+	}
+	return WriteZoneFile(w, zone, records)
+}
+
+// WriteZoneFile writes records, whose names are relative to zone, as a
+// BIND-style master file to w. The file starts with $ORIGIN and $TTL
+// directives, $TTL being the most common TTL of the records. Records are
+// sorted in canonical order (RFC 4034, section 6.1), then by type and data.
+// Target names are written fully-qualified, TXT data is quoted and split
+// into strings of at most 255 bytes. Zone and record names are written as
+// A-labels, since master files are ASCII (RFC 1035, section 5.1).
+//
+// The SOA and NS records of the zone apex are managed by IONOS and can not
+// be changed using the API. They are written as comments.
+func WriteZoneFile(w io.Writer, zone string, records []libdns.Record) error {
+	origin := toASCII(unFQDN(zone)) + "."
+
+	rrs := make([]libdns.RR, len(records))
+	for i, r := range records {
+		rrs[i] = r.RR()
+		rrs[i].Name = libdns.RelativeName(toASCII(libdns.AbsoluteName(rrs[i].Name, origin)), origin)
+	}
+	sort.SliceStable(rrs, func(i, j int) bool {
+		a, b := rrs[i], rrs[j]
+		if ka, kb := canonicalKey(a.Name), canonicalKey(b.Name); ka != kb {
+			return ka < kb
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Data < b.Data
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "$ORIGIN %s\n", origin)
+	fmt.Fprintf(bw, "$TTL %d\n", int64(defaultTTL(rrs).Seconds()))
+
+	var managed, other []libdns.RR
+	for _, rr := range rrs {
+		if isManagedRecord(rr) {
+			managed = append(managed, rr)
+		} else {
+			other = append(other, rr)
+		}
+	}
+	if len(managed) > 0 {
+		fmt.Fprintln(bw, "; managed by IONOS:")
+		for _, rr := range managed {
+			fmt.Fprintf(bw, "; %s\n", formatRR(rr))
+		}
+	}
+	for _, rr := range other {
+		fmt.Fprintln(bw, formatRR(rr))
+	}
+	return bw.Flush()
+}
+
+// canonicalKey returns a sort key for the relative name, which orders names
+// like RFC 4034: by label, starting with the rightmost one.
+func canonicalKey(name string) string {
+	if name == "@" || name == "" {
+		return ""
+	}
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	// the separator sorts before all valid label characters
+	return "\x00" + strings.Join(labels, "\x00")
+}
+
+// defaultTTL returns the most common TTL of rrs, preferring the smaller TTL
+// on ties, or 1 hour if rrs is empty.
+func defaultTTL(rrs []libdns.RR) time.Duration {
+	counts := make(map[time.Duration]int)
+	best := time.Hour
+	for _, rr := range rrs {
+		counts[rr.TTL]++
+		if n := counts[rr.TTL]; n > counts[best] || n == counts[best] && rr.TTL < best {
+			best = rr.TTL
+		}
+	}
+	return best
+}
+
+// formatRR formats rr as a line of a master file.
+func formatRR(rr libdns.RR) string {
+	name := rr.Name
+	if name == "" {
+		name = "@"
+	}
+	return fmt.Sprintf("%s\t%d\tIN\t%s\t%s", name, int64(rr.TTL.Seconds()), rr.Type, formatData(rr))
+}
+
+// formatData returns the RDATA of rr in master file format.
+func formatData(rr libdns.RR) string {
+	switch rr.Type {
+	case "TXT":
+		return quoteTXT(rr.Data)
+	case "CNAME", "NS", "PTR", "DNAME":
+		return absoluteTarget(rr.Data)
+	case "MX":
+		// preference target
+		if fields := strings.Fields(rr.Data); len(fields) == 2 {
+			return fields[0] + " " + absoluteTarget(fields[1])
+		}
+	case "SRV":
+		// priority weight port target
+		if fields := strings.Fields(rr.Data); len(fields) == 4 {
+			return strings.Join(fields[:3], " ") + " " + absoluteTarget(fields[3])
+		}
+	}
+	return rr.Data
+}
+
+// absoluteTarget adds a trailing dot to name, unless it has one already.
+// IONOS stores target names without trailing dot.
+func absoluteTarget(name string) string {
+	if name == "" || strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// quoteTXT quotes text as one or more character-strings of at most 255
+// bytes (RFC 1035, section 3.3.14), escaping quotes, backslashes and
+// non-printable characters.
+func quoteTXT(text string) string {
+	var chunks []string
+	for len(text) > 255 {
+		chunks = append(chunks, text[:255])
+		text = text[255:]
+	}
+	chunks = append(chunks, text)
+
+	var sb strings.Builder
+	for i, chunk := range chunks {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteByte('"')
+		for j := 0; j < len(chunk); j++ {
+			c := chunk[j]
+			switch {
+			case c == '"' || c == '\\':
+				sb.WriteByte('\\')
+				sb.WriteByte(c)
+			case c < ' ' || c > '~':
+				fmt.Fprintf(&sb, "\\%03d", c)
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		sb.WriteByte('"')
+	}
+	return sb.String()
+}
@@ -0,0 +1,96 @@
+package ionos
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_WriteZoneFile(t *testing.T) {
+	records := []libdns.Record{
+		libdns.TXT{Name: "b", Text: `v=spf1 "quoted" \ end`, TTL: time.Hour},
+		libdns.RR{Name: "@", Type: "SOA", Data: "ns1.ui-dns.de. hostmaster.1und1.com. 2024 28800 7200 604800 300", TTL: 24 * time.Hour},
+		libdns.CNAME{Name: "www.a", Target: "a.example.com", TTL: 5 * time.Minute},
+		libdns.NS{Name: "@", Target: "ns1.ui-dns.de", TTL: 24 * time.Hour},
+		libdns.NS{Name: "sub", Target: "ns.other.net", TTL: time.Hour},
+		libdns.MX{Name: "@", Preference: 10, Target: "mx.example.com", TTL: time.Hour},
+		libdns.RR{Name: "a", Type: "A", Data: "1.2.3.4", TTL: time.Hour},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteZoneFile(&buf, "example.com.", records); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `$ORIGIN example.com.
+$TTL 3600
+; managed by IONOS:
+; @	86400	IN	NS	ns1.ui-dns.de.
+; @	86400	IN	SOA	ns1.ui-dns.de. hostmaster.1und1.com. 2024 28800 7200 604800 300
+@	3600	IN	MX	10 mx.example.com.
+a	3600	IN	A	1.2.3.4
+www.a	300	IN	CNAME	a.example.com.
+b	3600	IN	TXT	"v=spf1 \"quoted\" \\ end"
+sub	3600	IN	NS	ns.other.net.
+`
+	if buf.String() != want {
+		t.Fatalf("unexpected zone file:\n%s\nexpected:\n%s", buf.String(), want)
+	}
+}
+
+func Test_quoteTXT(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	testCases := []struct {
+		text string
+		want string
+	}{
+		{text: "", want: `""`},
+		{text: "hello world", want: `"hello world"`},
+		{text: "tab\there", want: `"tab\009here"`},
+		{text: long, want: `"` + long[:255] + `" "` + long[255:] + `"`},
+	}
+	for _, c := range testCases {
+		if got := quoteTXT(c.text); got != c.want {
+			t.Errorf("quoteTXT(%q): expected %s, got %s", c.text, c.want, got)
+		}
+	}
+}
+
+func Test_ExportZone(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+	fs.addRecord(zone, "@", "TXT", "hello", 3600)
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	var buf bytes.Buffer
+	if err := p.ExportZone(context.TODO(), "example.com", &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "$ORIGIN example.com.\n$TTL 300\n@\t3600\tIN\tTXT\t\"hello\"\nwww\t300\tIN\tA\t1.2.3.4\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected zone file:\n%q\nexpected:\n%q", buf.String(), want)
+	}
+}
+
+func Test_ExportZoneUnicodeNames(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "xn--bcher-kva.de")
+	fs.addRecord(zone, "xn--caf-dma", "A", "1.2.3.4", 300)
+
+	// master files always use A-labels, even if names are returned in
+	// Unicode otherwise
+	p := &Provider{AuthAPIToken: "public.secret", UnicodeNames: true}
+	var buf bytes.Buffer
+	if err := p.ExportZone(context.TODO(), "bücher.de", &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "$ORIGIN xn--bcher-kva.de.\n$TTL 300\nxn--caf-dma\t300\tIN\tA\t1.2.3.4\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected zone file:\n%q\nexpected:\n%q", buf.String(), want)
+	}
+}
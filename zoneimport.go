@@ -0,0 +1,209 @@
+// RFC 1035 zone file import
+package ionos
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+)
+
+// ReadZoneFile parses a BIND-style master file (RFC 1035, section 5) into
+// records with names relative to zone. $ORIGIN and $TTL directives,
+// multi-line records in parentheses and relative names are supported.
+// filename is used in error messages and may be empty.
+//
+// $INCLUDE directives read arbitrary local files, so they are rejected
+// unless allowInclude is true; only allow them for trusted zone files.
+// $INCLUDE paths are resolved relative to filename.
+//
+// Records whose owner is neither the zone apex nor below it are rejected
+// with an error naming the line. The SOA and NS records of the zone apex
+// are skipped, since they are managed by IONOS. Target names of CNAME, MX, NS, PTR and SRV records are
+// returned without trailing dot, which is how IONOS stores them.
+func ReadZoneFile(r io.Reader, zone, filename string, allowInclude bool) ([]libdns.Record, error) {
+	origin := unFQDN(zone) + "."
+	lr := &lineReader{Reader: bufio.NewReader(r), line: 1}
+	zp := dns.NewZoneParser(lr, origin, filename)
+	zp.SetIncludeAllowed(allowInclude)
+
+	var records []libdns.Record
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if owner := rr.Header().Name; !inZone(owner, origin) {
+			pos := fmt.Sprintf("line %d", lr.line)
+			if filename != "" {
+				pos = filename + ": " + pos
+			}
+			return nil, fmt.Errorf("parse zone file: %s: %s is not in zone %s", pos, owner, origin)
+		}
+		record, err := fromDNSRR(rr, origin)
+		if err != nil {
+			return nil, err
+		}
+		if isManagedRecord(record.RR()) {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("parse zone file: %w", err)
+	}
+	return records, nil
+}
+
+// lineReader counts the lines read by the zone parser, which reads byte by
+// byte if its reader is an io.ByteReader. After a record is parsed, line is
+// the line it ends on; records of $INCLUDE files report the line of the
+// $INCLUDE directive.
+type lineReader struct {
+	*bufio.Reader
+	line int
+	eol  bool
+}
+
+func (r *lineReader) ReadByte() (byte, error) {
+	c, err := r.Reader.ReadByte()
+	if err == nil {
+		// count a newline when the next line starts, like the parser
+		if r.eol {
+			r.line++
+		}
+		r.eol = c == '\n'
+	}
+	return c, err
+}
+
+// fromDNSRR converts a record parsed by miekg/dns to a libdns record with a
+// name relative to origin.
+func fromDNSRR(rr dns.RR, origin string) (libdns.Record, error) {
+	hdr := rr.Header()
+	result := libdns.RR{
+		Name: relativeName(hdr.Name, origin),
+		TTL:  time.Duration(hdr.Ttl) * time.Second,
+		Type: dns.TypeToString[hdr.Rrtype],
+	}
+
+	switch v := rr.(type) {
+	case *dns.TXT:
+		text, err := unescapeTXT(strings.Join(v.Txt, ""))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+		result.Data = text
+	case *dns.CNAME:
+		result.Data = unFQDN(v.Target)
+	case *dns.NS:
+		result.Data = unFQDN(v.Ns)
+	case *dns.PTR:
+		result.Data = unFQDN(v.Ptr)
+	case *dns.MX:
+		result.Data = fmt.Sprintf("%d %s", v.Preference, unFQDN(v.Mx))
+	case *dns.SRV:
+		result.Data = fmt.Sprintf("%d %d %d %s", v.Priority, v.Weight, v.Port, unFQDN(v.Target))
+	default:
+		result.Data = strings.TrimPrefix(rr.String(), hdr.String())
+	}
+
+	record, err := result.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("convert record %s %s: %w", hdr.Name, result.Type, err)
+	}
+	return record, nil
+}
+
+// unescapeTXT resolves the \X and \DDD escapes in a TXT character-string
+// as returned by miekg/dns.
+func unescapeTXT(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i+3 < len(s) && isDigit(s[i+1]) && isDigit(s[i+2]) && isDigit(s[i+3]) {
+			n, err := strconv.Atoi(s[i+1 : i+4])
+			if err != nil || n > 255 {
+				return "", fmt.Errorf("invalid escape sequence %q", s[i:i+4])
+			}
+			sb.WriteByte(byte(n))
+			i += 3
+			continue
+		}
+		if i+1 < len(s) {
+			sb.WriteByte(s[i+1])
+			i++
+		}
+	}
+	return sb.String(), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// ImportOptions control ImportZone.
+type ImportOptions struct {
+	// Filename of the zone file, used to resolve $INCLUDE directives and in
+	// error messages.
+	Filename string
+
+	// AllowInclude enables $INCLUDE directives, which read arbitrary local
+	// files. Only enable it for trusted zone files.
+	AllowInclude bool
+
+	// Replace makes the zone contain exactly the records of the zone file.
+	// Existing records which are not in the zone file are deleted, except
+	// for the SOA and apex NS records managed by IONOS. Without Replace,
+	// the records are appended to the zone.
+	Replace bool
+}
+
+// ImportResult lists the changes made by ImportZone.
+type ImportResult struct {
+	Created []libdns.Record
+	Updated []libdns.Record
+	Deleted []libdns.Record
+}
+
+// ImportZone reads a BIND-style master file (see ReadZoneFile) and adds its
+// records to zone. With opts.Replace, the zone is synced to the records of
+// the file using PlanSync and ApplySync: records already present are left
+// untouched or updated in place, records not in the file are deleted.
+func (p *Provider) ImportZone(ctx context.Context, zone string, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	records, err := ReadZoneFile(r, zone, opts.Filename, opts.AllowInclude)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if !opts.Replace {
+		created, err := p.AppendRecords(ctx, zone, records)
+		return ImportResult{Created: created}, err
+	}
+
+	plan, err := p.PlanSync(ctx, zone, records, SyncOptions{})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	applied, err := p.ApplySync(ctx, plan)
+
+	var result ImportResult
+	for _, c := range applied {
+		switch c.Action {
+		case SyncCreate:
+			result.Created = append(result.Created, c.New)
+		case SyncUpdate:
+			result.Updated = append(result.Updated, c.New)
+		case SyncDelete:
+			result.Deleted = append(result.Deleted, c.Old)
+		}
+	}
+	return result, err
+}
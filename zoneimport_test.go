@@ -0,0 +1,135 @@
+package ionos
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func Test_ReadZoneFile(t *testing.T) {
+	dir := t.TempDir()
+	included := "mail 600 IN A 10.0.0.1\n"
+	if err := os.WriteFile(filepath.Join(dir, "mail.inc"), []byte(included), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	zoneFile := `$ORIGIN example.com.
+$TTL 3600
+@	IN	SOA	ns1.ui-dns.de. hostmaster.example.com. (
+		2024010101 ; serial
+		28800      ; refresh
+		7200       ; retry
+		604800     ; expire
+		300 )      ; minimum
+@	IN	NS	ns1.ui-dns.de.
+@	IN	MX	10 mail
+www	300	IN	CNAME	example.com.
+txt	IN	TXT	( "part one "
+		"part \"two\"" )
+$INCLUDE mail.inc
+$ORIGIN sub.example.com.
+host	IN	AAAA	2001:db8::1
+@	IN	NS	ns.other.net.
+`
+	records, err := ReadZoneFile(strings.NewReader(zoneFile), "example.com", filepath.Join(dir, "example.com.zone"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []libdns.RR{
+		{Name: "@", TTL: time.Hour, Type: "MX", Data: "10 mail.example.com"},
+		{Name: "www", TTL: 5 * time.Minute, Type: "CNAME", Data: "example.com"},
+		{Name: "txt", TTL: time.Hour, Type: "TXT", Data: `part one part "two"`},
+		{Name: "mail", TTL: 10 * time.Minute, Type: "A", Data: "10.0.0.1"},
+		{Name: "host.sub", TTL: time.Hour, Type: "AAAA", Data: "2001:db8::1"},
+		{Name: "sub", TTL: time.Hour, Type: "NS", Data: "ns.other.net"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d records, got %d: %+v", len(want), len(records), records)
+	}
+	for i, r := range records {
+		if r.RR() != want[i] {
+			t.Errorf("record %d: expected %+v, got %+v", i, want[i], r.RR())
+		}
+	}
+}
+
+func Test_ReadZoneFileSyntaxError(t *testing.T) {
+	_, err := ReadZoneFile(strings.NewReader("www IN A not-an-ip\n"), "example.com", "test.zone", false)
+	if err == nil || !strings.Contains(err.Error(), "test.zone") {
+		t.Fatalf("expected parse error naming the file, got %v", err)
+	}
+}
+
+func Test_ReadZoneFileOutOfZone(t *testing.T) {
+	tests := []struct {
+		zoneFile string
+		want     string
+	}{
+		{"www IN A 1.2.3.4\nfoo.other.org. IN A 1.2.3.5\n", "test.zone: line 2: foo.other.org. is not in zone example.com."},
+		{"txt IN TXT ( \"a\"\n \"b\" )\n$ORIGIN other.org.\nfoo IN A 1.2.3.5", "line 4: foo.other.org."},
+		{"notexample.com. IN A 1.2.3.5\n", "line 1: notexample.com."},
+	}
+	for _, tt := range tests {
+		_, err := ReadZoneFile(strings.NewReader(tt.zoneFile), "example.com", "test.zone", false)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("expected error containing %q, got %v", tt.want, err)
+		}
+	}
+}
+
+func Test_ReadZoneFileIncludeNotAllowed(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mail.inc"), []byte("mail IN A 10.0.0.1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	zoneFile := "$INCLUDE mail.inc\n"
+	filename := filepath.Join(dir, "example.com.zone")
+	if _, err := ReadZoneFile(strings.NewReader(zoneFile), "example.com", filename, false); err == nil {
+		t.Fatal("expected $INCLUDE to be rejected")
+	}
+
+	p := &Provider{AuthAPIToken: "public.secret"}
+	if _, err := p.ImportZone(context.TODO(), "example.com", strings.NewReader(zoneFile), ImportOptions{Filename: filename}); err == nil {
+		t.Fatal("expected $INCLUDE to be rejected by ImportZone")
+	}
+}
+
+func Test_ImportZoneReplace(t *testing.T) {
+	fs := newFakeServer(t)
+	zone := fs.addZone("public.secret", "example.com")
+	fs.addRecord(zone, "@", "NS", "ns1.ui-dns.de", 86400)
+	keep := fs.addRecord(zone, "www", "A", "1.2.3.4", 300)
+	fs.addRecord(zone, "old", "A", "1.2.3.5", 300)
+
+	zoneFile := `$TTL 300
+www	IN	A	1.2.3.4
+new	IN	TXT	"hello"
+`
+	p := &Provider{AuthAPIToken: "public.secret"}
+	result, err := p.ImportZone(context.TODO(), "example.com", strings.NewReader(zoneFile), ImportOptions{Replace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Created) != 1 || result.Created[0].RR().Name != "new" {
+		t.Errorf("expected record new to be created, got %+v", result.Created)
+	}
+	if len(result.Deleted) != 1 || result.Deleted[0].RR().Name != "old" {
+		t.Errorf("expected record old to be deleted, got %+v", result.Deleted)
+	}
+
+	records := fs.records(zone)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records in zone, got %+v", records)
+	}
+	for _, r := range records {
+		if r.Name == "www.example.com" && r.ID != keep.ID {
+			t.Errorf("expected unchanged record to be kept, but it was recreated")
+		}
+	}
+}